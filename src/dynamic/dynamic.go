@@ -0,0 +1,185 @@
+// Package dynamic reads and writes the packets of a protocol definition parsed at runtime,
+// without generated code. The wire format is the one of the code generated by the go
+// backend, so both sides can talk to each other.
+//
+// Packet fields are kept in a map[string]interface{} keyed by field name:
+//
+//	integer kinds     int8, int16, int32, int64, byte, uint16, uint32, uint64
+//	string            string
+//	[]byte, [N]byte   []byte
+//	other slices      []interface{} of the element values
+//	other arrays      []interface{} of N element values
+//	struct            map[string]interface{}
+//
+// Read returns values of exactly these types. Write is more lenient, so that values
+// decoded by encoding/json or written by hand are accepted as well: integers may be of
+// any Go integer type, json.Number, an integral float64, a bool or a numeric string in
+// the syntax of strconv.ParseInt with base 0; byte fields may be a base64 string or a
+// list of integers. Like in the generated New<Name>(), missing fields and nil values are
+// written as their default annotation, except within the elements of slices, or else as
+// zero values.
+package dynamic
+
+import (
+	"errors"
+	"fmt"
+	"generator"
+	"stream"
+)
+
+var ErrUnknownPacket = errors.New("unknown packet type")
+
+// Header is the packet header preceding the fields of every packet.
+type Header struct {
+	ID         uint32
+	PacketType uint32
+	Len        uint32
+	Version    uint32
+	Ack        uint32
+	Token      uint32
+}
+
+func (h *Header) fields() []*uint32 {
+	return []*uint32{&h.ID, &h.PacketType, &h.Len, &h.Version, &h.Ack, &h.Token}
+}
+
+func (h *Header) Read(s stream.ReadStream) error {
+	for _, field := range h.fields() {
+		var err error
+		if *field, err = s.ReadUint32(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h *Header) Write(s stream.WriteStream) error {
+	for _, field := range h.fields() {
+		if err := s.WriteUint32(*field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Packet is a packet of the schema, Layout is its definition.
+type Packet struct {
+	Header
+	Layout *generator.PacketLayout
+	Fields map[string]interface{}
+}
+
+// Codec reads and writes the packets of a schema.
+type Codec struct {
+	schema *generator.Schema
+	// Limits are checked when reading, like the Limits of the generated code.
+	Limits generator.DecodeLimits
+}
+
+func NewCodec(schema *generator.Schema) *Codec {
+	return &Codec{schema: schema, Limits: generator.DefaultDecodeLimits}
+}
+
+func (c *Codec) Schema() *generator.Schema { return c.schema }
+
+// New returns an empty packet, name is the ID name or the type name of the packet.
+func (c *Codec) New(name string) (*Packet, error) {
+	layout := c.schema.LookupIDName(name)
+	if layout == nil {
+		if layout = c.schema.Lookup(name); layout == nil || !layout.IsPacket() {
+			return nil, fmt.Errorf("%w %q", ErrUnknownPacket, name)
+		}
+	}
+	return &Packet{
+		Header: Header{PacketType: layout.ID()},
+		Layout: layout,
+		Fields: make(map[string]interface{}),
+	}, nil
+}
+
+// CreatePacket reads a packet header and the fields of the packet type it names,
+// like CreatePacket of the generated PacketFactory.
+func (c *Codec) CreatePacket(s stream.ReadStream) (*Packet, error) {
+	var header Header
+	if err := header.Read(s); err != nil {
+		return nil, err
+	}
+	if err := c.Limits.CheckTotal(header.Len); err != nil {
+		return nil, err
+	}
+	layout := c.schema.LookupID(header.PacketType)
+	if layout == nil || !layout.IsPacket() {
+		return nil, ErrUnknownPacket
+	}
+	fields, err := c.Read(layout, s)
+	if err != nil {
+		return nil, err
+	}
+	return &Packet{Header: header, Layout: layout, Fields: fields}, nil
+}
+
+// Length returns the length of the packet as counted by Len, including the header
+// and the reserved bytes after the fields, see generator.PacketHeaderLength.
+func (c *Codec) Length(p *Packet) (int, error) {
+	values, err := c.encode(p.Layout, p.Fields)
+	if err != nil {
+		return 0, err
+	}
+	return generator.PacketHeaderLength + values.size, nil
+}
+
+// AdjustLength sets the Len of the header to the length of the packet.
+func (c *Codec) AdjustLength(p *Packet) error {
+	length, err := c.Length(p)
+	if err != nil {
+		return err
+	}
+	p.Len = uint32(length)
+	return nil
+}
+
+// Marshal returns the encoded packet of Len bytes, the header is written as it is and the
+// reserved bytes after the fields are zero.
+func (c *Codec) Marshal(p *Packet, endian string) ([]byte, error) {
+	values, err := c.encode(p.Layout, p.Fields)
+	if err != nil {
+		return nil, err
+	}
+	data := make([]byte, generator.PacketHeaderLength+values.size)
+	if err = c.writePacket(p, values, NewWriteStream(endian, data)); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// Write writes the header and the fields of the packet, like Write of a generated packet.
+func (c *Codec) Write(p *Packet, s stream.WriteStream) error {
+	values, err := c.encode(p.Layout, p.Fields)
+	if err != nil {
+		return err
+	}
+	return c.writePacket(p, values, s)
+}
+
+func (c *Codec) writePacket(p *Packet, values *wireValues, s stream.WriteStream) error {
+	if err := p.Header.Write(s); err != nil {
+		return err
+	}
+	return values.write(s)
+}
+
+// NewReadStream returns a stream of the byte order "big" or "little".
+func NewReadStream(endian string, data []byte) stream.ReadStream {
+	if endian == "little" {
+		return stream.NewLittleEndianStream(data)
+	}
+	return stream.NewBigEndianStream(data)
+}
+
+// NewWriteStream returns a stream of the byte order "big" or "little".
+func NewWriteStream(endian string, data []byte) stream.WriteStream {
+	if endian == "little" {
+		return stream.NewLittleEndianStream(data)
+	}
+	return stream.NewBigEndianStream(data)
+}
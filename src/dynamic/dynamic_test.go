@@ -0,0 +1,272 @@
+package dynamic
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"generator"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const allKindsSchema = `package protocol
+
+type Empty struct{}
+
+type Point struct {
+	X   int32
+	Y   int16
+	Tag string
+}
+
+// @Packet: ALL, 0x00000001
+type All struct {
+	I8      int8
+	I16     int16
+	I32     int32
+	I64     int64
+	B       byte
+	U8      uint8
+	U16     uint16
+	U32     uint32
+	U64     uint64
+	S       string
+	P       Point
+	E       Empty
+	Raw     []byte
+	Nums    []int16
+	Names   []string
+	Points  []Point
+	Empties []Empty
+	Fix     [3]byte
+	Arr     [2]uint64
+	Labels  [2]string
+	Corners [2]Point
+}
+`
+
+const allKindsJSON = `{
+	"I8": -2, "I16": -300, "I32": -70000, "I64": -5000000000,
+	"B": 255, "U8": 128, "U16": 65535, "U32": 4000000000, "U64": 18446744073709551615,
+	"S": "héllo", "P": {"X": -1, "Y": 2, "Tag": "p"}, "E": {},
+	"Raw": "AQID", "Nums": [1, -1], "Names": ["a", ""], "Points": [{"X": 3, "Y": -4, "Tag": ""}], "Empties": [{}, {}],
+	"Fix": [7, 8, 9], "Arr": [1, 72623859790382856], "Labels": ["x", "yz"],
+	"Corners": [{"X": 5, "Y": 6, "Tag": "c"}, {"X": 0, "Y": 0, "Tag": ""}]
+}`
+
+// allKindsFrames are the frames of allKindsJSON with the header {ID: 7, Version: 2, Ack: 3,
+// Token: 4} written by the code the go backend generates from allKindsSchema.
+var allKindsFrames = map[string]string{
+	"big": "0000000700000001000000b9000000020000000300000004fefed4fffeee90fffffffed5fa0e00ff80ffffee6b2800ffffffffffffffff" +
+		"0000000668c3a96c6c6fffffffff0002000000017000000003010203000000020001ffff00000002000000016100000000000000010000" +
+		"0003fffc000000000000000207080900000000000000010102030405060708000000017800000002797a00000005000600000001630000" +
+		"0000000000000000000000000000000000000000",
+	"little": "0700000001000000b9000000020000000300000004000000fed4fe90eefeff000efad5feffffffff80ffff00286beeffffffffffffffff" +
+		"0600000068c3a96c6c6fffffffff02000100000070030000000102030200000001" +
+		"00ffff020000000100000061000000000100000003000000fcff000000000200000007080901000000000000000807060504030201" +
+		"010000007802000000797a050000000600010000006300000000000000000000000000000000000000000000",
+}
+
+// allKindsFields are the fields of allKindsJSON as Read returns them.
+var allKindsFields = map[string]interface{}{
+	"I8": int8(-2), "I16": int16(-300), "I32": int32(-70000), "I64": int64(-5000000000),
+	"B": byte(255), "U8": byte(128), "U16": uint16(65535), "U32": uint32(4000000000), "U64": uint64(18446744073709551615),
+	"S":       "héllo",
+	"P":       map[string]interface{}{"X": int32(-1), "Y": int16(2), "Tag": "p"},
+	"E":       map[string]interface{}{},
+	"Raw":     []byte{1, 2, 3},
+	"Nums":    []interface{}{int16(1), int16(-1)},
+	"Names":   []interface{}{"a", ""},
+	"Points":  []interface{}{map[string]interface{}{"X": int32(3), "Y": int16(-4), "Tag": ""}},
+	"Empties": []interface{}{map[string]interface{}{}, map[string]interface{}{}},
+	"Fix":     []byte{7, 8, 9},
+	"Arr":     []interface{}{uint64(1), uint64(72623859790382856)},
+	"Labels":  []interface{}{"x", "yz"},
+	"Corners": []interface{}{
+		map[string]interface{}{"X": int32(5), "Y": int16(6), "Tag": "c"},
+		map[string]interface{}{"X": int32(0), "Y": int16(0), "Tag": ""},
+	},
+}
+
+func TestRoundTrip(t *testing.T) {
+	schema, err := generator.ParseSchemaSource([]byte(allKindsSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	codec := NewCodec(schema)
+	for _, endian := range []string{"big", "little"} {
+		t.Run(endian, func(t *testing.T) {
+			want, _ := hex.DecodeString(allKindsFrames[endian])
+			p, err := codec.New("ALL")
+			if err != nil {
+				t.Fatal(err)
+			}
+			decoder := json.NewDecoder(strings.NewReader(allKindsJSON))
+			decoder.UseNumber()
+			if err = decoder.Decode(&p.Fields); err != nil {
+				t.Fatal(err)
+			}
+			p.ID, p.Version, p.Ack, p.Token = 7, 2, 3, 4
+			if err = codec.AdjustLength(p); err != nil {
+				t.Fatal(err)
+			}
+			data, err := codec.Marshal(p, endian)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(data, want) {
+				t.Fatalf("Marshal differs from the generated code\ngot  %x\nwant %x", data, want)
+			}
+			read, err := codec.CreatePacket(NewReadStream(endian, want))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if read.Header != p.Header {
+				t.Errorf("header %+v, want %+v", read.Header, p.Header)
+			}
+			if !reflect.DeepEqual(read.Fields, allKindsFields) {
+				t.Errorf("fields %#v\nwant %#v", read.Fields, allKindsFields)
+			}
+			if data, err = codec.Marshal(read, endian); err != nil || !bytes.Equal(data, want) {
+				t.Errorf("Marshal of the read packet: %x, %v", data, err)
+			}
+		})
+	}
+}
+
+const lengthsSchema = `package protocol
+
+type Empty struct{}
+
+// @Packet: LENGTHS, 0x00000002
+type Lengths struct {
+	S       string ` + "`goproto:\"max=4\"`" + `
+	Raw     []byte
+	Nums    []uint16
+	Empties []Empty
+}
+`
+
+func TestReadLengths(t *testing.T) {
+	schema, err := generator.ParseSchemaSource([]byte(lengthsSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	codec := NewCodec(schema)
+	layout := codec.Schema().Lookup("Lengths")
+	tests := []struct {
+		name string
+		body string
+		// path is the field the error names, it is empty if the body is valid
+		path string
+		// limit is the Limit of a *generator.LimitError, if the error is one
+		limit string
+	}{
+		{"valid", "00000002616200000001ff000000020001000200000003", "", ""},
+		{"truncated string prefix", "000000", "Lengths.S", ""},
+		{"truncated string", "000000036162", "Lengths.S", ""},
+		{"string over max", "0000000561626364650000000000000000000000000000", "Lengths.S", "max"},
+		{"truncated byte slice prefix", "0000000000", "Lengths.Raw", ""},
+		{"truncated byte slice", "0000000000000003ffff", "Lengths.Raw", ""},
+		{"oversized byte slice prefix", "00000000ffffffff00000000", "Lengths.Raw", "MaxStringBytes"},
+		{"truncated slice", "0000000000000000000000030001", "Lengths.Nums", "Left"},
+		{"oversized slice prefix", "000000000000000080000000", "Lengths.Nums", "MaxElements"},
+		{"oversized empty struct prefix", "000000000000000000000000ffffffff", "Lengths.Empties", "MaxElements"},
+		{"truncated empty struct prefix", "000000000000000000000000ff", "Lengths.Empties", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := hex.DecodeString(tt.body)
+			_, err := codec.Read(layout, NewReadStream("big", body))
+			if len(tt.path) == 0 {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			var e *DecodeError
+			if !errors.As(err, &e) {
+				t.Fatalf("got %v, want a DecodeError", err)
+			}
+			if e.Path != tt.path {
+				t.Errorf("error %q names %s, want %s", err, e.Path, tt.path)
+			}
+			var limit *generator.LimitError
+			if errors.As(err, &limit) != (len(tt.limit) != 0) || (limit != nil && limit.Limit != tt.limit) {
+				t.Errorf("error %q, want limit %q", err, tt.limit)
+			}
+		})
+	}
+}
+
+// A count of field-less structs is not bounded by the data left, it must not be allocated.
+func TestReadEmptyStructCount(t *testing.T) {
+	schema, err := generator.ParseSchemaSource([]byte(`package protocol
+
+type E struct{}
+
+// @Packet: P, 0x00000001
+type P struct{ L []E }
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	codec := NewCodec(schema)
+	frame, _ := hex.DecodeString("00000000000000010000001c000000000000000000000000ffffffff")
+	var limit *generator.LimitError
+	if _, err := codec.CreatePacket(NewReadStream("big", frame)); !errors.As(err, &limit) || limit.Limit != "MaxElements" {
+		t.Fatalf("got %v, want a MaxElements LimitError", err)
+	}
+	frame, _ = hex.DecodeString("00000000000000010000001c00000000000000000000000000000003")
+	p, err := codec.CreatePacket(NewReadStream("big", frame))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := p.Fields["L"].([]interface{}); len(l) != 3 {
+		t.Errorf("got %d elements, want 3", len(l))
+	}
+}
+
+// New compares names exactly, like PacketFromJSON of the generated code.
+func TestNewName(t *testing.T) {
+	schema, err := generator.ParseSchemaSource([]byte(allKindsSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	codec := NewCodec(schema)
+	for name, ok := range map[string]bool{"ALL": true, "All": true, "all": false, "ALl": false, "Point": false} {
+		if _, err := codec.New(name); (err == nil) != ok {
+			t.Errorf("New(%q) returned %v", name, err)
+		}
+	}
+}
+
+func TestCodecLimits(t *testing.T) {
+	schema, err := generator.ParseSchemaSource([]byte(lengthsSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	codec := NewCodec(schema)
+	codec.Limits = generator.DecodeLimits{MaxElements: 2, MaxStringBytes: 3, MaxTotalBytes: 100}
+	tests := []struct {
+		frame string
+		limit string
+	}{
+		{"000000000000000200000030000000000000000000000000" + "00000003616263000000000000000200010002000000020000000000000000000000000000", ""},
+		{"000000000000000200000065000000000000000000000000", "MaxTotalBytes"},
+		{"000000000000000200000030000000000000000000000000" + "000000046162636400000000", "MaxStringBytes"},
+		{"000000000000000200000030000000000000000000000000" + "000000000000000400000000", "MaxStringBytes"},
+		{"000000000000000200000030000000000000000000000000" + "000000000000000000000003000100020003", "MaxElements"},
+		{"000000000000000200000030000000000000000000000000" + "00000000000000000000000000000003", "MaxElements"},
+	}
+	for _, tt := range tests {
+		frame, _ := hex.DecodeString(tt.frame)
+		_, err := codec.CreatePacket(NewReadStream("big", frame))
+		var limit *generator.LimitError
+		if len(tt.limit) == 0 && err != nil || len(tt.limit) != 0 && (!errors.As(err, &limit) || limit.Limit != tt.limit) {
+			t.Errorf("%s: got %v, want limit %q", tt.frame, err, tt.limit)
+		}
+	}
+}
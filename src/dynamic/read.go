@@ -0,0 +1,170 @@
+package dynamic
+
+import (
+	"fmt"
+	"generator"
+	"stream"
+)
+
+// DecodeError reports the field which could not be read and its offset in the stream.
+type DecodeError struct {
+	Path   string
+	Offset int
+	Err    error
+}
+
+func (e *DecodeError) Error() string {
+	if l, ok := e.Err.(*generator.LimitError); ok && l.Field == e.Path {
+		return fmt.Sprintf("%s at offset %d", l, e.Offset)
+	}
+	return fmt.Sprintf("%s at offset %d: %s", e.Path, e.Offset, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error { return e.Err }
+
+// Read reads the fields of a packet or a struct, the packet header must have been read.
+// On error the returned map holds the fields read before the failing one, and the error
+// is a *DecodeError naming it.
+func (c *Codec) Read(layout *generator.PacketLayout, s stream.ReadStream) (map[string]interface{}, error) {
+	r := &reader{schema: c.schema, limits: c.Limits, stream: s}
+	return r.fields(layout, layout.Name())
+}
+
+type reader struct {
+	schema *generator.Schema
+	limits generator.DecodeLimits
+	stream stream.ReadStream
+}
+
+func (r *reader) offset() int { return r.stream.Size() - r.stream.Left() }
+
+func (r *reader) fail(path string, offset int, err error) error {
+	if _, ok := err.(*DecodeError); ok {
+		return err
+	}
+	if err == stream.ErrBuffOverflow {
+		err = fmt.Errorf("unexpected end of data, %d bytes left", r.stream.Left())
+	}
+	return &DecodeError{Path: path, Offset: offset, Err: err}
+}
+
+func (r *reader) fields(p *generator.PacketLayout, path string) (map[string]interface{}, error) {
+	fields := make(map[string]interface{}, len(p.Fields()))
+	for _, f := range p.Fields() {
+		v, err := r.field(f, path+"."+f.Name())
+		if v != nil {
+			fields[f.Name()] = v
+		}
+		if err != nil {
+			return fields, err
+		}
+	}
+	return fields, nil
+}
+
+// field returns the value of f, on error it is nil or a partially read struct or list of structs.
+func (r *reader) field(f *generator.FieldLayout, path string) (interface{}, error) {
+	offset := r.offset()
+	if f.Kind() != generator.SliceFieldKind && f.Kind() != generator.ArrayFieldKind {
+		return r.value(f.Kind(), f.TypeName(), f.Path(), path, f.LengthMax())
+	}
+	count := f.ArrayLen()
+	if f.Kind() == generator.SliceFieldKind {
+		size, err := r.stream.ReadUint32()
+		if err != nil {
+			return nil, r.fail(path, offset, err)
+		}
+		if k := f.ElemKind(); k == generator.ByteFieldKind || k == generator.Uint8FieldKind {
+			err = r.limits.CheckBytes(f.Path(), size, f.LengthMax())
+		} else {
+			minSize := r.schema.MinWireSize(f.ElemKind(), f.TypeName())
+			err = r.limits.CheckCount(f.Path(), size, f.LengthMax(), minSize, r.stream.Left())
+		}
+		if err != nil {
+			return nil, r.fail(path, offset, err)
+		}
+		count = int(size)
+	}
+	if k := f.ElemKind(); k == generator.ByteFieldKind || k == generator.Uint8FieldKind {
+		b, err := r.stream.ReadBuff(count)
+		if err != nil {
+			return nil, r.fail(path, offset, err)
+		}
+		return b, nil
+	}
+	// Elements of structs without fields take no bytes, the data left doesn't bound their count.
+	values := make([]interface{}, 0, min(count, r.stream.Left()))
+	for i := 0; i < count; i++ {
+		v, err := r.value(f.ElemKind(), f.TypeName(), f.Path(), fmt.Sprintf("%s[%d]", path, i), -1)
+		if err != nil {
+			if f.ElemKind() != generator.StructFieldKind {
+				return nil, err
+			}
+			if v != nil {
+				values = append(values, v)
+			}
+			return values, err
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+// value reads a single value of kind k, typeName is the struct name of a struct kind.
+// field is the qualified name of the field the limits report and max its annotation
+// limiting the length of a string, it is -1 if there is none.
+func (r *reader) value(k generator.FieldKind, typeName, field, path string, max int) (interface{}, error) {
+	offset := r.offset()
+	switch k {
+	case generator.StructFieldKind:
+		p := r.schema.Lookup(typeName)
+		if p == nil {
+			return nil, r.fail(path, offset, fmt.Errorf("unknown struct %s", typeName))
+		}
+		return r.fields(p, path)
+	case generator.StringFieldKind:
+		size, err := r.stream.ReadUint32()
+		if err != nil {
+			return nil, r.fail(path, offset, err)
+		}
+		if err = r.limits.CheckBytes(field, size, max); err != nil {
+			return nil, r.fail(path, offset, err)
+		}
+		b, err := r.stream.ReadBuff(int(size))
+		if err != nil {
+			return nil, r.fail(path, offset, fmt.Errorf("string of %d bytes, %d left", size, r.stream.Left()))
+		}
+		return string(b), nil
+	}
+	v, err := readInt(r.stream, k)
+	if err != nil {
+		return nil, r.fail(path, offset, err)
+	}
+	return v, nil
+}
+
+func readInt(s stream.ReadStream, k generator.FieldKind) (interface{}, error) {
+	switch k {
+	case generator.ByteFieldKind, generator.Uint8FieldKind:
+		return s.ReadByte()
+	case generator.Int8FieldKind:
+		v, err := s.ReadByte()
+		return int8(v), err
+	case generator.Uint16FieldKind:
+		return s.ReadUint16()
+	case generator.Int16FieldKind:
+		v, err := s.ReadUint16()
+		return int16(v), err
+	case generator.Uint32FieldKind:
+		return s.ReadUint32()
+	case generator.Int32FieldKind:
+		v, err := s.ReadUint32()
+		return int32(v), err
+	case generator.Uint64FieldKind:
+		return s.ReadUint64()
+	case generator.Int64FieldKind:
+		v, err := s.ReadUint64()
+		return int64(v), err
+	}
+	return nil, fmt.Errorf("unsupported field kind %s", k)
+}
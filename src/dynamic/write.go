@@ -0,0 +1,249 @@
+package dynamic
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"generator"
+	"math"
+	"reflect"
+	"strconv"
+	"stream"
+)
+
+// wireValues is a packet body checked against the schema and converted into the values
+// written to the stream, size is their encoded size.
+type wireValues struct {
+	schema *generator.Schema
+	values []interface{}
+	size   int
+}
+
+// wireInt is an integer of the given size in bytes, signed values are stored as two's complement.
+type wireInt struct {
+	size  int
+	value uint64
+}
+
+// wireCount is the uint32 length or count preceding strings and slices.
+type wireCount uint32
+
+func (c *Codec) encode(layout *generator.PacketLayout, fields map[string]interface{}) (*wireValues, error) {
+	if layout == nil {
+		return nil, ErrUnknownPacket
+	}
+	w := &wireValues{schema: c.schema}
+	if err := w.structValue(layout, fields, layout.Name(), true); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// structValue adds the fields of struct p. If defaults is set a missing field takes its default
+// annotation like in New<Name>(), which does not apply within the elements of slices.
+func (w *wireValues) structValue(p *generator.PacketLayout, value interface{}, path string, defaults bool) error {
+	var members map[string]interface{}
+	if value != nil {
+		var ok bool
+		if members, ok = value.(map[string]interface{}); !ok {
+			return fmt.Errorf("%s: expected an object, got %s", path, Kind(value))
+		}
+	}
+	known := make(map[string]bool)
+	for _, f := range p.Fields() {
+		known[f.Name()] = true
+		value := members[f.Name()]
+		if v, ok := f.Annotation("default"); ok && value == nil && defaults {
+			value = v
+		}
+		if err := w.field(f, value, path+"."+f.Name(), defaults); err != nil {
+			return err
+		}
+	}
+	for name := range members {
+		if !known[name] {
+			return fmt.Errorf("%s: unknown field %q", path, name)
+		}
+	}
+	return nil
+}
+
+func (w *wireValues) field(f *generator.FieldLayout, value interface{}, path string, defaults bool) error {
+	if f.Kind() != generator.SliceFieldKind && f.Kind() != generator.ArrayFieldKind {
+		return w.value(f.Kind(), f.TypeName(), value, path, defaults)
+	}
+	isByte := f.ElemKind() == generator.ByteFieldKind || f.ElemKind() == generator.Uint8FieldKind
+	var items []interface{}
+	switch v := value.(type) {
+	case nil:
+	case []interface{}:
+		items = v
+	case []byte:
+		if !isByte {
+			return fmt.Errorf("%s: expected a list, got %s", path, Kind(value))
+		}
+		return w.bytes(f, v, path)
+	case string:
+		if !isByte {
+			return fmt.Errorf("%s: expected a list, got %s", path, Kind(value))
+		}
+		b, err := base64.StdEncoding.DecodeString(v)
+		if err != nil {
+			return fmt.Errorf("%s: invalid base64: %s", path, err)
+		}
+		return w.bytes(f, b, path)
+	default:
+		return fmt.Errorf("%s: expected a list, got %s", path, Kind(value))
+	}
+	if f.Kind() == generator.SliceFieldKind {
+		w.add(wireCount(len(items)), 4)
+		defaults = false
+	} else if len(items) != f.ArrayLen() {
+		if value != nil {
+			return fmt.Errorf("%s: expected %d elements, got %d", path, f.ArrayLen(), len(items))
+		}
+		items = make([]interface{}, f.ArrayLen())
+	}
+	for i, item := range items {
+		if err := w.value(f.ElemKind(), f.TypeName(), item, fmt.Sprintf("%s[%d]", path, i), defaults); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *wireValues) bytes(f *generator.FieldLayout, b []byte, path string) error {
+	if f.Kind() == generator.SliceFieldKind {
+		w.add(wireCount(len(b)), 4)
+	} else if len(b) != f.ArrayLen() {
+		return fmt.Errorf("%s: expected %d bytes, got %d", path, f.ArrayLen(), len(b))
+	}
+	w.add(b, len(b))
+	return nil
+}
+
+func (w *wireValues) value(k generator.FieldKind, typeName string, value interface{}, path string, defaults bool) error {
+	switch k {
+	case generator.StructFieldKind:
+		p := w.schema.Lookup(typeName)
+		if p == nil {
+			return fmt.Errorf("%s: unknown struct %s", path, typeName)
+		}
+		return w.structValue(p, value, path, defaults)
+	case generator.StringFieldKind:
+		s, ok := value.(string)
+		if !ok && value != nil {
+			return fmt.Errorf("%s: expected a string, got %s", path, Kind(value))
+		}
+		w.add(wireCount(len(s)), 4)
+		w.add([]byte(s), len(s))
+		return nil
+	}
+	v, err := ToInteger(value, k)
+	if err != nil {
+		return fmt.Errorf("%s: %s", path, err)
+	}
+	w.add(wireInt{k.Size(), v}, k.Size())
+	return nil
+}
+
+func (w *wireValues) add(value interface{}, size int) {
+	w.values = append(w.values, value)
+	w.size += size
+}
+
+func (w *wireValues) write(s stream.WriteStream) error {
+	var err error
+	for _, value := range w.values {
+		switch v := value.(type) {
+		case wireCount:
+			err = s.WriteUint32(uint32(v))
+		case []byte:
+			err = s.WriteBuff(v)
+		case wireInt:
+			switch v.size {
+			case 1:
+				err = s.WriteByte(byte(v.value))
+			case 2:
+				err = s.WriteUint16(uint16(v.value))
+			case 4:
+				err = s.WriteUint32(uint32(v.value))
+			default:
+				err = s.WriteUint64(v.value)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ToInteger converts an integer of any Go type, a json.Number, an integral float64,
+// a numeric string or a bool into the wire value of kind k, signed values are returned
+// as two's complement of the kind's size.
+func ToInteger(value interface{}, k generator.FieldKind) (uint64, error) {
+	var text string
+	switch v := value.(type) {
+	case nil:
+		return 0, nil
+	case bool:
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	case json.Number:
+		text = string(v)
+	case string:
+		text = v
+	case float64:
+		if v != math.Trunc(v) || math.IsInf(v, 0) {
+			return 0, fmt.Errorf("invalid %s %v", k, v)
+		}
+		text = strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		rv := reflect.ValueOf(value)
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			text = strconv.FormatInt(rv.Int(), 10)
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			text = strconv.FormatUint(rv.Uint(), 10)
+		default:
+			return 0, fmt.Errorf("expected an integer, got %s", Kind(value))
+		}
+	}
+	bits := uint(k.Size() * 8)
+	if k.IsSigned() {
+		n, err := strconv.ParseInt(text, 0, int(bits))
+		if err != nil {
+			return 0, fmt.Errorf("invalid %s %q", k, text)
+		}
+		return uint64(n) & (1<<bits - 1), nil
+	}
+	n, err := strconv.ParseUint(text, 0, int(bits))
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q", k, text)
+	}
+	return n, nil
+}
+
+// Kind describes the type of a field value in error messages, in JSON terms.
+func Kind(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case map[string]interface{}:
+		return "an object"
+	case []interface{}:
+		return "a list"
+	case []byte:
+		return "bytes"
+	case string:
+		return "a string"
+	case json.Number, float64:
+		return "a number"
+	case bool:
+		return "a bool"
+	}
+	return fmt.Sprintf("%T", value)
+}
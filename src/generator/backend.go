@@ -0,0 +1,108 @@
+package generator
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Backend turns a parsed protocol schema into source files of a target language.
+// The returned map is keyed by file name, the value is the file content.
+type Backend interface {
+	Generate(schema *Schema, opts Options) (map[string][]byte, error)
+}
+
+// Options carries backend specific settings, which are given by the command line
+// as key=value pairs. Backends ignore the keys they don't know.
+type Options map[string]string
+
+func (o Options) Get(key, def string) string {
+	if v, ok := o[key]; ok && len(v) != 0 {
+		return v
+	}
+	return def
+}
+
+func (o Options) Bool(key string) bool {
+	switch strings.ToLower(o[key]) {
+	case "1", "true", "yes", "on":
+		return true
+	}
+	return false
+}
+
+// Endian returns the byte order selected by the option "endian", which is "big" by default.
+func (o Options) Endian() (string, error) {
+	switch endian := strings.ToLower(o.Get("endian", "big")); endian {
+	case "big", "little":
+		return endian, nil
+	default:
+		return "", fmt.Errorf("invalid endian %q, must be big or little", endian)
+	}
+}
+
+// DecodeLimits returns DefaultDecodeLimits overridden by the options "maxelements",
+// "maxstringbytes" and "maxtotalbytes", 0 disables a limit.
+func (o Options) DecodeLimits() (DecodeLimits, error) {
+	limits := DefaultDecodeLimits
+	for key, limit := range map[string]*int{
+		"maxelements":    &limits.MaxElements,
+		"maxstringbytes": &limits.MaxStringBytes,
+		"maxtotalbytes":  &limits.MaxTotalBytes,
+	} {
+		value, ok := o[key]
+		if !ok {
+			continue
+		}
+		n, err := strconv.ParseInt(value, 0, 32)
+		if err != nil || n < 0 {
+			return limits, fmt.Errorf("invalid %s %q, must be a non-negative integer", key, value)
+		}
+		*limit = int(n)
+	}
+	return limits, nil
+}
+
+var backends = make(map[string]Backend)
+
+// RegisterBackend makes a backend available by name, it is normally called in init().
+func RegisterBackend(name string, backend Backend) {
+	if backend == nil {
+		panic("generator: RegisterBackend backend is nil")
+	}
+	if _, dup := backends[name]; dup {
+		panic("generator: RegisterBackend called twice for backend " + name)
+	}
+	backends[name] = backend
+}
+
+func LookupBackend(name string) (Backend, error) {
+	backend, ok := backends[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown backend %q, available: %s", name, strings.Join(BackendNames(), ", "))
+	}
+	return backend, nil
+}
+
+func BackendNames() []string {
+	names := make([]string, 0, len(backends))
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GenerateWith parses the protocol file and runs the named backend on it.
+func GenerateWith(lang, filePath string, opts Options) (map[string][]byte, error) {
+	backend, err := LookupBackend(lang)
+	if err != nil {
+		return nil, err
+	}
+	schema, err := ParseSchema(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return backend.Generate(schema, opts)
+}
@@ -0,0 +1,213 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata/golden")
+
+// backendCase generates testdata/protocol.go with a backend, the output is kept in
+// testdata/golden/<name>.
+type backendCase struct {
+	name string
+	lang string
+	opts Options
+}
+
+var backendCases = []backendCase{
+	{"c", "c", nil},
+	{"cpp", "cpp", nil},
+	{"csharp", "csharp", nil},
+	{"docs", "docs", nil},
+	{"go", "go", nil},
+	{"go-tests", "go", Options{"tests": "true"}},
+	{"go-options", "go", Options{"json": "true", "string": "true", "descriptors": "true", "validate": "true", "tests": "true", "fuzz": "true"}},
+	{"jsonschema", "jsonschema", nil},
+	{"jsonschema-openapi", "jsonschema", Options{"openapi": "true"}},
+	{"kaitai", "kaitai", nil},
+	{"lua", "lua", nil},
+	{"proto", "proto", nil},
+	{"python", "python", nil},
+	{"typescript", "typescript", nil},
+	{"wireshark", "wireshark", nil},
+}
+
+func generateCase(t *testing.T, c backendCase) map[string][]byte {
+	t.Helper()
+	files, err := GenerateWith(c.lang, filepath.Join("testdata", "protocol.go"), c.opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func TestBackendCases(t *testing.T) {
+	covered := make(map[string]bool)
+	for _, c := range backendCases {
+		covered[c.lang] = true
+	}
+	for _, name := range BackendNames() {
+		if !covered[name] {
+			t.Errorf("backend %s has no case in backendCases", name)
+		}
+	}
+}
+
+func TestGolden(t *testing.T) {
+	for _, c := range backendCases {
+		t.Run(c.name, func(t *testing.T) {
+			files := generateCase(t, c)
+			dir := filepath.Join("testdata", "golden", c.name)
+			if *update {
+				if err := os.RemoveAll(dir); err != nil {
+					t.Fatal(err)
+				}
+				if err := writeTestFiles(dir, files); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			infos, err := ioutil.ReadDir(dir)
+			if err != nil {
+				t.Fatalf("%s, run go test -update to create the golden files", err)
+			}
+			var names []string
+			for _, info := range infos {
+				names = append(names, info.Name())
+			}
+			var generated []string
+			for name := range files {
+				generated = append(generated, name)
+			}
+			sort.Strings(generated)
+			if strings.Join(names, " ") != strings.Join(generated, " ") {
+				t.Fatalf("generated %v, want %v", generated, names)
+			}
+			for _, name := range names {
+				want, err := ioutil.ReadFile(filepath.Join(dir, name))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(files[name], want) {
+					t.Errorf("%s differs from %s, run go test -update if the change is intended", name, filepath.Join(dir, name))
+				}
+			}
+		})
+	}
+}
+
+// TestCompile checks the generated code with the compiler of each language, tools missing
+// from PATH are skipped.
+func TestCompile(t *testing.T) {
+	for _, c := range backendCases {
+		t.Run(c.name, func(t *testing.T) {
+			files := generateCase(t, c)
+			dir := t.TempDir()
+			if err := writeTestFiles(dir, files); err != nil {
+				t.Fatal(err)
+			}
+			switch c.lang {
+			case "go":
+				compileGo(t, dir, files)
+			case "c":
+				runTool(t, dir, "gcc", "-std=c99", "-Wall", "-Werror", "-c", "protocol.c")
+			case "cpp":
+				runTool(t, dir, "g++", "-std=c++17", "-Wall", "-Werror", "-c", "protocol.cpp")
+			case "python":
+				runTool(t, dir, "python3", "-m", "py_compile", "protocol.py")
+			case "typescript":
+				runTool(t, dir, "tsc", "--noEmit", "--strict", "--target", "es2020", "protocol.ts")
+			case "lua":
+				runTool(t, dir, "luac", "-p", "protocol.lua")
+			case "wireshark":
+				runTool(t, dir, "luac", "-p", "protocol_dissector.lua")
+			case "csharp":
+				runTool(t, dir, "mcs", "-target:library", "-out:"+filepath.Join(dir, "Protocol.dll"), "Protocol.cs")
+			case "kaitai":
+				runTool(t, dir, "ksc", "-t", "python", "-d", dir, "protocol.ksy")
+			case "jsonschema":
+				for name, data := range files {
+					if !json.Valid(data) {
+						t.Errorf("%s is not valid JSON", name)
+					}
+				}
+			case "proto":
+				if _, err := ImportProto(files["protocol.proto"], nil); err != nil {
+					t.Error(err)
+				}
+			case "docs":
+				// documentation only
+			default:
+				t.Fatalf("no compile check for %s", c.lang)
+			}
+		})
+	}
+}
+
+// handWrittenPacket is a Packet implemented by hand with the methods of the header, no option
+// may add methods it lacks to the interface.
+const handWrittenPacket = `package protocol
+
+type handWritten struct{ PacketHeader }
+
+var _ Packet = (*handWritten)(nil)
+`
+
+// compileGo vets and tests the generated package together with a copy of the stream
+// package and handWrittenPacket, in a GOPATH of its own.
+func compileGo(t *testing.T, dir string, files map[string][]byte) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go not found")
+	}
+	stream, err := ioutil.ReadFile(filepath.Join("..", "stream", "stream.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream = bytes.Replace(stream, []byte("package stream"), []byte("package protocol"), 1)
+	pkg := filepath.Join(dir, "src", "protocol")
+	files["stream.go"] = stream
+	files["handwritten_test.go"] = []byte(handWrittenPacket)
+	if err = writeTestFiles(pkg, files); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{{"vet", "protocol"}, {"test", "protocol"}} {
+		cmd := exec.Command("go", args...)
+		cmd.Dir = pkg
+		cmd.Env = append(os.Environ(), "GOPATH="+dir, "GO111MODULE=off", "GOFLAGS=")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("go %s: %s\n%s", args[0], err, out)
+		}
+	}
+}
+
+func runTool(t *testing.T, dir, tool string, args ...string) {
+	if _, err := exec.LookPath(tool); err != nil {
+		t.Skip(tool + " not found")
+	}
+	cmd := exec.Command(tool, args...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("%s: %s\n%s", tool, err, out)
+	}
+}
+
+func writeTestFiles(dir string, files map[string][]byte) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for name, data := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
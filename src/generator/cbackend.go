@@ -0,0 +1,108 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+func init() {
+	RegisterBackend("c", cBackend{})
+}
+
+// cBackend generates a C header and source with plain structs and
+// encode/decode functions working on a bounds checked byte buffer.
+type cBackend struct{}
+
+func (cBackend) Generate(schema *Schema, opts Options) (map[string][]byte, error) {
+	endian, err := opts.Endian()
+	if err != nil {
+		return nil, err
+	}
+	prefix := strings.ToLower(schema.PackageName)
+	t, err := loadTemplates("c", opts, template.FuncMap{
+		"prefix": func() string { return prefix },
+		"cField": func(f *FieldLayout) string { return cField(prefix, f) },
+		"cType":  func(k FieldKind, typeName string) string { return cType(prefix, k, typeName) },
+		"cShort": cShort,
+		"items": func(f *FieldLayout) string {
+			if f.kind == SliceFieldKind {
+				return "p->" + f.name + ".items"
+			}
+			return "p->" + f.name
+		},
+		"minWireSize": func(k FieldKind, typeName string) int {
+			return schema.MinWireSize(k, typeName)
+		},
+		"isDynamic": func(k FieldKind, typeName string) bool {
+			return isDynamic(schema, k, typeName)
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	data := &templateData{Schema: schema, Options: opts, Endian: endian}
+	header, err := executeTemplate(t, "header", data)
+	if err != nil {
+		return nil, err
+	}
+	source, err := executeTemplate(t, "source", data)
+	if err != nil {
+		return nil, err
+	}
+	return map[string][]byte{
+		prefix + ".h": header,
+		prefix + ".c": source,
+	}, nil
+}
+
+// cShort returns the short name of an integer kind, which is used by the buffer helpers.
+func cShort(k FieldKind) string {
+	if k.IsSigned() {
+		return fmt.Sprintf("i%d", k.Size()*8)
+	}
+	return fmt.Sprintf("u%d", k.Size()*8)
+}
+
+// cType returns the C type of a single value of kind k.
+func cType(prefix string, k FieldKind, typeName string) string {
+	switch k {
+	case StringFieldKind:
+		return prefix + "_string"
+	case StructFieldKind:
+		return typeName
+	}
+	if k.IsSigned() {
+		return fmt.Sprintf("int%d_t", k.Size()*8)
+	}
+	return fmt.Sprintf("uint%d_t", k.Size()*8)
+}
+
+// cField returns the C declaration of a struct member.
+func cField(prefix string, f *FieldLayout) string {
+	elem := cType(prefix, f.subElementKind, f.fieldType)
+	switch f.kind {
+	case SliceFieldKind:
+		return fmt.Sprintf("struct {\n        uint32_t len;\n        %s *items;\n    } %s", elem, f.name)
+	case ArrayFieldKind:
+		return fmt.Sprintf("%s %s[%d]", elem, f.name, f.arrayLen)
+	}
+	return cType(prefix, f.kind, f.fieldType) + " " + f.name
+}
+
+// isDynamic reports whether a value of kind k owns memory after decoding.
+func isDynamic(schema *Schema, k FieldKind, typeName string) bool {
+	switch k {
+	case StringFieldKind, SliceFieldKind:
+		return true
+	case StructFieldKind:
+		if s := schema.Lookup(typeName); s != nil {
+			for _, f := range s.fields {
+				if isDynamic(schema, f.kind, f.fieldType) || isDynamic(schema, f.subElementKind, f.fieldType) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+func init() {
+	RegisterBackend("cpp", cppBackend{})
+}
+
+// cppBackend generates C++17 classes which serialize themselves by the ReadStream and
+// WriteStream classes, which are generated as well, together with a PacketFactory.
+type cppBackend struct{}
+
+func (cppBackend) Generate(schema *Schema, opts Options) (map[string][]byte, error) {
+	name := strings.ToLower(schema.PackageName)
+	t, err := loadTemplates("cpp", opts, template.FuncMap{
+		"fileName": func() string { return name },
+		"cppType":  cppType,
+		"cppElemType": func(k FieldKind, typeName string) string {
+			return cppElemType(k, typeName)
+		},
+		"minWireSize": func(p *PacketLayout) int {
+			return schema.MinWireSize(StructFieldKind, p.name)
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	data := &templateData{Schema: schema, Options: opts}
+	header, err := executeTemplate(t, "header", data)
+	if err != nil {
+		return nil, err
+	}
+	source, err := executeTemplate(t, "source", data)
+	if err != nil {
+		return nil, err
+	}
+	return map[string][]byte{
+		name + ".hpp": header,
+		name + ".cpp": source,
+	}, nil
+}
+
+// cppElemType returns the C++ type of a single value of kind k.
+func cppElemType(k FieldKind, typeName string) string {
+	switch k {
+	case StringFieldKind:
+		return "std::string"
+	case StructFieldKind:
+		return typeName
+	}
+	if k.IsSigned() {
+		return fmt.Sprintf("int%d_t", k.Size()*8)
+	}
+	return fmt.Sprintf("uint%d_t", k.Size()*8)
+}
+
+// cppType returns the declared C++ type of a field.
+func cppType(f *FieldLayout) string {
+	elem := cppElemType(f.subElementKind, f.fieldType)
+	switch f.kind {
+	case SliceFieldKind:
+		return "std::vector<" + elem + ">"
+	case ArrayFieldKind:
+		return fmt.Sprintf("std::array<%s, %d>", elem, f.arrayLen)
+	}
+	return cppElemType(f.kind, f.fieldType)
+}
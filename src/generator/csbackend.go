@@ -0,0 +1,137 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+func init() {
+	RegisterBackend("csharp", csBackend{})
+}
+
+// csBackend generates C# classes for Unity, they serialize themselves by BinaryReader
+// and BinaryWriter in the byte order selected by the option "endian".
+type csBackend struct{}
+
+func (csBackend) Generate(schema *Schema, opts Options) (map[string][]byte, error) {
+	endian, err := opts.Endian()
+	if err != nil {
+		return nil, err
+	}
+	namespace := opts.Get("namespace", strings.ToUpper(schema.PackageName[:1])+schema.PackageName[1:])
+	t, err := loadTemplates("csharp", opts, template.FuncMap{
+		"namespace": func() string { return namespace },
+		"csType":    csType,
+		"csRead":    csRead,
+		"csWrite":   csWrite,
+		"csLength":  csLength,
+		"csDefault": csDefault,
+		"isByte":    func(k FieldKind) bool { return k == ByteFieldKind || k == Uint8FieldKind },
+		"minWireSize": func(k FieldKind, typeName string) int {
+			return schema.MinWireSize(k, typeName)
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	code, err := executeTemplate(t, "file", &templateData{Schema: schema, Options: opts, Endian: endian})
+	if err != nil {
+		return nil, err
+	}
+	return map[string][]byte{namespace + ".cs": code}, nil
+}
+
+var csTypes = map[FieldKind]string{
+	ByteFieldKind:   "byte",
+	Uint8FieldKind:  "byte",
+	Uint16FieldKind: "ushort",
+	Uint32FieldKind: "uint",
+	Uint64FieldKind: "ulong",
+	Int8FieldKind:   "sbyte",
+	Int16FieldKind:  "short",
+	Int32FieldKind:  "int",
+	Int64FieldKind:  "long",
+	StringFieldKind: "string",
+}
+
+var csMethods = map[FieldKind]string{
+	ByteFieldKind:   "Byte",
+	Uint8FieldKind:  "Byte",
+	Uint16FieldKind: "UInt16",
+	Uint32FieldKind: "UInt32",
+	Uint64FieldKind: "UInt64",
+	Int8FieldKind:   "SByte",
+	Int16FieldKind:  "Int16",
+	Int32FieldKind:  "Int32",
+	Int64FieldKind:  "Int64",
+	StringFieldKind: "String",
+}
+
+func csElemType(k FieldKind, typeName string) string {
+	if k == StructFieldKind {
+		return typeName
+	}
+	return csTypes[k]
+}
+
+// csType returns the declared type of a field, slices and arrays are both C# arrays.
+func csType(f *FieldLayout) string {
+	switch f.kind {
+	case SliceFieldKind, ArrayFieldKind:
+		return csElemType(f.subElementKind, f.fieldType) + "[]"
+	}
+	return csElemType(f.kind, f.fieldType)
+}
+
+// csRead returns the expression reading a single value of kind k from the BinaryReader r.
+func csRead(k FieldKind, typeName string) string {
+	if k == StructFieldKind {
+		return typeName + ".ReadFrom(r)"
+	}
+	return "Wire.Read" + csMethods[k] + "(r)"
+}
+
+// csWrite returns the statement writing the value v of kind k to the BinaryWriter w.
+func csWrite(k FieldKind, v string) string {
+	if k == StructFieldKind {
+		return v + ".Write(w);"
+	}
+	return "Wire.Write" + csMethods[k] + "(w, " + v + ");"
+}
+
+// csLength returns the expression computing the wire size of the value v of kind k.
+func csLength(k FieldKind, v string) string {
+	switch k {
+	case StructFieldKind:
+		return v + ".Length()"
+	case StringFieldKind:
+		return "Wire.StringLength(" + v + ")"
+	}
+	return fmt.Sprint(k.Size())
+}
+
+// csDefault returns the initial value of a field.
+func csDefault(f *FieldLayout) string {
+	elem := csElemType(f.subElementKind, f.fieldType)
+	switch f.kind {
+	case SliceFieldKind:
+		return "new " + elem + "[0]"
+	case ArrayFieldKind:
+		if f.subElementKind == StructFieldKind || f.subElementKind == StringFieldKind {
+			return fmt.Sprintf("Wire.NewArray(%d, () => %s)", f.arrayLen, csElemDefault(f.subElementKind, f.fieldType))
+		}
+		return fmt.Sprintf("new %s[%d]", elem, f.arrayLen)
+	}
+	return csElemDefault(f.kind, f.fieldType)
+}
+
+func csElemDefault(k FieldKind, typeName string) string {
+	switch k {
+	case StructFieldKind:
+		return "new " + typeName + "()"
+	case StringFieldKind:
+		return `""`
+	}
+	return "0"
+}
@@ -47,4 +47,32 @@ Now just support follows data type:
 	struct, one-dimensional slice, one-dimensional array.
 	Note: Now not support []struct and []string
 
+Fields may carry annotations in a goproto struct tag, the keys are separated by commas
+and a value containing commas is enclosed in single quotes:
+
+type LoginRequest struct {
+	UserName string `goproto:"min=1,max=32"`
+	Age      uint32 `goproto:"max=200"`
+}
+
+min and max bound the length of a string, the element count of a slice, or the value of an integer.
+len gives the exact length of a string or element count of a slice, pattern is a regular
+expression a string must match, and required asks for a non-empty string or slice or a
+non-zero integer:
+
+type LoginRequest struct {
+	UserName string `goproto:"required,max=32,pattern='^[a-z][a-z0-9_]*$'"`
+	Code     string `goproto:"len=4"`
+}
+
+The Go backend option validate generates a Validate method checking these rules.
+
+default gives the initial value of a string or integer field, New<Name> and the generated
+Reset method set it, a nested struct gets the defaults of its own fields:
+
+type LoginRequest struct {
+	UserName string `goproto:"default=guest"`
+	Age      uint32 `goproto:"default=18"`
+}
+
 */
@@ -0,0 +1,128 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+func init() {
+	RegisterBackend("docs", docsBackend{})
+}
+
+// docsBackend renders a protocol reference, as Markdown and as a self-contained HTML page.
+// Every packet and struct is listed with its doc comment and a table of its fields, the
+// offsets are given as long as the fields before have a fixed size.
+type docsBackend struct{}
+
+func (docsBackend) Generate(schema *Schema, opts Options) (map[string][]byte, error) {
+	endian, err := opts.Endian()
+	if err != nil {
+		return nil, err
+	}
+	t, err := loadTemplates("docs", opts, template.FuncMap{
+		"docFields": func(p *PacketLayout) []*docField { return docFields(schema, p) },
+		"mdCell":    func(s string) string { return strings.Replace(strings.Replace(s, "|", "\\|", -1), "\n", " ", -1) },
+		"anchor":    strings.ToLower,
+		"mul":       func(a, b int) int { return a * b },
+	})
+	if err != nil {
+		return nil, err
+	}
+	data := &templateData{Schema: schema, Options: opts, Endian: endian}
+	files := make(map[string][]byte)
+	for name, ext := range map[string]string{"markdown": ".md", "html": ".html"} {
+		code, err := executeTemplate(t, name, data)
+		if err != nil {
+			return nil, err
+		}
+		files[schema.PackageName+ext] = code
+	}
+	return files, nil
+}
+
+// docField is a row of the field table.
+type docField struct {
+	Name string
+	// Type is the declared Go type, Struct is set if its element is a struct.
+	Type   string
+	Struct string
+	// Wire describes the encoding of variable sized types.
+	Wire string
+	// Offset is empty once a field before has variable size.
+	Offset string
+	Size   string
+	Doc    string
+}
+
+func docFields(schema *Schema, p *PacketLayout) []*docField {
+	offset, fixed := 0, true
+	if p.IsPacket() {
+		offset = PacketHeaderSize
+	}
+	rows := make([]*docField, 0, len(p.fields))
+	for _, f := range p.fields {
+		row := &docField{Name: f.name, Type: goType(f), Doc: f.doc}
+		if f.subElementKind == StructFieldKind {
+			row.Struct = f.fieldType
+		}
+		switch {
+		case f.kind == SliceFieldKind:
+			row.Wire = "uint32 count, then the elements"
+		case f.kind == StringFieldKind:
+			row.Wire = "uint32 length, then the UTF-8 bytes"
+		case f.kind == ArrayFieldKind && f.subElementKind == StringFieldKind:
+			row.Wire = fmt.Sprintf("%d strings", f.arrayLen)
+		}
+		if fixed {
+			row.Offset = fmt.Sprint(offset)
+		}
+		if size, ok := docFixedSize(schema, f); ok {
+			row.Size = fmt.Sprint(size)
+			offset += size
+		} else {
+			row.Size = fmt.Sprintf("%d+", docMinSize(schema, f))
+			fixed = false
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func docMinSize(schema *Schema, f *FieldLayout) int {
+	if f.kind == ArrayFieldKind {
+		return f.arrayLen * schema.MinWireSize(f.subElementKind, f.fieldType)
+	}
+	return schema.MinWireSize(f.kind, f.fieldType)
+}
+
+// docFixedSize returns the wire size of a field, ok is false if the size depends on the value.
+func docFixedSize(schema *Schema, f *FieldLayout) (size int, ok bool) {
+	switch f.kind {
+	case StringFieldKind, SliceFieldKind:
+		return 0, false
+	case ArrayFieldKind, StructFieldKind:
+		if f.subElementKind == StringFieldKind {
+			return 0, false
+		}
+		size = f.subElementKind.Size()
+		if f.subElementKind == StructFieldKind {
+			p := schema.Lookup(f.fieldType)
+			if p == nil {
+				return 0, false
+			}
+			for _, sub := range p.fields {
+				n, ok := docFixedSize(schema, sub)
+				if !ok {
+					return 0, false
+				}
+				size += n
+			}
+		}
+		if f.kind == ArrayFieldKind {
+			size *= f.arrayLen
+		}
+		return size, true
+	}
+	return f.kind.Size(), true
+}
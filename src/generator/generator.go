@@ -2,699 +2,250 @@ package generator
 
 import (
 	"fmt"
-	"go/ast"
 	"go/format"
+	"math/bits"
 	"strconv"
 	"strings"
+	"text/template"
 )
 
-func Generate(filePath string) (data []byte, err error) {
-	parser, err := NewProtoParser(filePath)
+func init() {
+	RegisterBackend("go", goBackend{})
+}
+
+// goBackend is the default backend, it generates the Go packets
+// which read and write themselves by ReadStream and WriteStream.
+// The code is produced by the templates in templates/go.
+type goBackend struct{}
+
+func (goBackend) Generate(schema *Schema, opts Options) (map[string][]byte, error) {
+	t, err := loadGoTemplates(schema, opts)
 	if err != nil {
 		return nil, err
 	}
-	if err = parser.Parse(); err != nil {
+	data, err := executeGoTemplate(t, "file", schema, opts)
+	if err != nil {
 		return nil, err
 	}
-
-	packageName := "\npackage protocol"
-	if parser.astFile.Name != nil {
-		packageName = "package " + parser.astFile.Name.Name
-	}
-
-	content := packageName + "\n"
-	content += addImportPackageCode() + "\n"
-	content += addPacketID(parser.packets) + "\n"
-	content += addPacketInterfaceCode() + "\n"
-	content += addPacketHeaderCode() + "\n"
-
-	for _, p := range parser.packets {
-		var packetContent string
-		var err error
-		switch p.kind {
-		case StructKind:
-			packetContent, err = generateStruct(p)
-		case GenericPacketKind:
-			packetContent, err = generateGenericPacket(p)
-		case SimplePacketKind:
-			packetContent, err = generateSimplePacket(p)
-		case VLFPacketKind:
-			packetContent, err = generateVLFPacket(p)
-		}
-		if err != nil {
+	files := map[string][]byte{schema.PackageName + ".go": data}
+	if opts.Bool("tests") || opts.Bool("fuzz") {
+		if data, err = executeGoTemplate(t, "testFile", schema, opts); err != nil {
 			return nil, err
 		}
-		content += packetContent + "\n"
+		files[schema.PackageName+"_test.go"] = data
 	}
+	return files, nil
+}
 
-	packetFactoryCode, err := generatePacketFactory(parser.packets)
+func Generate(filePath string) (data []byte, err error) {
+	schema, err := ParseSchema(filePath)
 	if err != nil {
 		return nil, err
 	}
-	content += packetFactoryCode + "\n"
-	return format.Source([]byte(content))
+	return generateGoCode(schema, nil)
 }
 
-func addPacketHeaderCode() string {
-	return `
-	type PacketHeader struct {		
-		ID         uint32
-		PacketType uint32
-		Len        uint32
-		Version    uint32
-		Ack        uint32
-		Token      uint32
-	}
-
-	func (p *PacketHeader) GetID() uint32 { return p.ID }
-
-	func (p *PacketHeader) SetID(id uint32) { p.ID = id }
-
-	func (p *PacketHeader) GetToken() uint32 { return p.Token }
-
-	func (p *PacketHeader) SetToken(token uint32) { p.Token = token }
-
-	func (p *PacketHeader) GetAck() uint32 { return p.Ack }
-
-	func (p *PacketHeader) SetAck(ack uint32) { p.Ack = ack }
-
-	func (p *PacketHeader) GetPacketType() uint32 { return p.PacketType }
-
-	func (p *PacketHeader) Length() int { return 36 }
-	
-	func (p *PacketHeader) AdjustLength() { p.Len = uint32(p.Length()) }
-
-	func (p *PacketHeader) Read(stream ReadStream) error {
-		var err error
-		if p.ID, err = stream.ReadUint32(); err != nil {
-			return err
-		}
-		if p.PacketType, err = stream.ReadUint32(); err != nil {
-			return err
-		}
-		if p.Len, err = stream.ReadUint32(); err != nil {
-			return err
-		}
-		if p.Version, err = stream.ReadUint32(); err != nil {
-			return err
-		}
-		if p.Ack, err = stream.ReadUint32(); err != nil {
-			return err
-		}
-		if p.Token, err = stream.ReadUint32(); err != nil {
-			return err
-		}
-		return nil
-	}
+var goTemplateFuncs = template.FuncMap{
+	"goType":        goType,
+	"isByte":        func(k FieldKind) bool { return k == ByteFieldKind || k == Uint8FieldKind },
+	"streamType":    goStreamType,
+	"streamCast":    goStreamCast,
+	"lengthMax":     (*FieldLayout).LengthMax,
+	"goLimit":       goLimit,
+	"defaultLimits": func() DecodeLimits { return DefaultDecodeLimits },
+	"annotation":    func(f *FieldLayout, key string) string { return f.annotations[key] },
+	"quote":         strconv.Quote,
 
-	func (w *PacketHeader) Write(stream WriteStream) error {
-		var err error
-		if err = stream.WriteUint32(w.ID); err != nil {
-			return err
-		}
-		if err = stream.WriteUint32(w.PacketType); err != nil {
-			return err
-		}
-		if err = stream.WriteUint32(w.Len); err != nil {
-			return err
-		}
-		if err = stream.WriteUint32(w.Version); err != nil {
-			return err
-		}
-		if err = stream.WriteUint32(w.Ack); err != nil {
-			return err
-		}
-		if err = stream.WriteUint32(w.Token); err != nil {
-			return err
-		}
-		return nil
-	}`
-}
+	"usesAnnotation": goUsesAnnotation,
 
-func addImportPackageCode() string {
-	return `
-		import "errors"
-		
-		var ErrUnknownPacket = errors.New("unknown packet")
-	`
+	"descriptorFields": goDescriptorFields,
 }
 
-func addPacketID(packets []*PacketLayout) string {
-	if len(packets) != 0 {
-		content := "const (\n"
-		for _, p := range packets {
-			if p.kind != StructKind {
-				content += fmt.Sprintf("%s = 0x%08x\n", strings.ToUpper(p.idname), p.id)
-			}
-		}
-		content += ")"
-		return content
-
+// loadGoTemplates loads the Go templates together with the functions depending on the schema
+// and the options.
+func loadGoTemplates(schema *Schema, opts Options) (*template.Template, error) {
+	funcs := template.FuncMap{
+		"minWireSize":   func(f *FieldLayout) int { return schema.MinWireSize(f.subElementKind, f.fieldType) },
+		"defaultValues": func(p *PacketLayout) []goDefaultValue { return goDefaultValues(schema, p) },
+		"patternVar":    goPatternVars(schema),
 	}
-	return ""
-}
-
-func addPacketInterfaceCode() string {
-	return `
-	type Packet interface {
-		GetID() uint32
-		SetID(uint32)
-		GetToken() uint32
-		SetToken(uint32)
-		GetAck() uint32
-		SetAck(uint32)
-		GetPacketType() uint32
-		Length() int
-		AdjustLength()
-		Read(stream ReadStream) error
-		Write(stream WriteStream) error
-	}`
-}
-
-func getArrayTypeLength(field *ast.Field) (i int, err error) {
-	if a, ok := field.Type.(*ast.ArrayType); ok {
-		if b, ok := a.Len.(*ast.BasicLit); ok {
-			return strconv.Atoi(b.Value)
-		}
+	for name, fn := range goTemplateFuncs {
+		funcs[name] = fn
 	}
-
-	return 0, fmt.Errorf("invalid field")
-}
-
-func generateStructData(p *PacketLayout) (s string, err error) {
-	structContent := fmt.Sprintf("\ntype %s struct {\n", p.name)
-	if p.kind != StructKind {
-		structContent += "    PacketHeader\n"
-	}
-	for _, f := range p.fields {
-		if f.kind == ArrayFieldKind {
-			index, err := getArrayTypeLength(f.field)
-			if err != nil {
-				return "", err
-			}
-			structContent += fmt.Sprintf("    %s [%d]%s\n", f.name, index, f.fieldType)
-		} else if f.kind == SliceFieldKind {
-			structContent += fmt.Sprintf("    %s []%s\n", f.name, f.fieldType)
-		} else {
-			structContent += fmt.Sprintf("    %s %s\n", f.name, f.fieldType)
-		}
+	for name, fn := range goRandomFuncs(opts.Bool("validate")) {
+		funcs[name] = fn
 	}
-	structContent += fmt.Sprintf("\n}")
-	return structContent, nil
+	return loadTemplates("go", opts, funcs)
 }
 
-func generateStruct(p *PacketLayout) (s string, err error) {
-	structContent, err := generateStructData(p)
-	if err != nil {
-		return "", err
-	}
-
-	code, err := generatePacketLengthCode(p)
-	if err != nil {
-		return "", err
-	}
-	structContent += "\n" + code
-
-	code, err = generatePacketAdjustLengthCode(p)
+func generateGoCode(schema *Schema, opts Options) ([]byte, error) {
+	t, err := loadGoTemplates(schema, opts)
 	if err != nil {
-		return "", err
-	}
-	structContent += "\n" + code
-
-	code, err = generateReadCode(p)
-	if err != nil {
-		return "", err
-	}
-	structContent += "\n" + code
-
-	code, err = generateWriteCode(p)
-	if err != nil {
-		return "", err
+		return nil, err
 	}
-	structContent += "\n" + code
-
-	return structContent, nil
+	return executeGoTemplate(t, "file", schema, opts)
 }
 
-func generateSimplePacket(p *PacketLayout) (s string, err error) {
-	structContent, err := generateStructData(p)
+// executeGoTemplate executes the named template and formats the result as Go source.
+func executeGoTemplate(t *template.Template, name string, schema *Schema, opts Options) ([]byte, error) {
+	code, err := executeTemplate(t, name, &templateData{Schema: schema, Options: opts})
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-
-	structContent += fmt.Sprintf("\nfunc (s *%s) Length() int { return s.PacketHeader.Length() }", p.name)
-	structContent += fmt.Sprintf("\nfunc (s *%s) AdjustLength() { s.Len = uint32(s.Length()) }", p.name)
-	structContent += fmt.Sprintf("\nfunc (s *%s) Read(stream ReadStream) error { return nil}", p.name)
-	structContent += fmt.Sprintf("\nfunc (s *%s) Write(stream WriteStream) error { return s.PacketHeader.Write(stream) }", p.name)
-
-	code, err := generateNewPacketFunc(p)
+	data, err := format.Source(code)
 	if err != nil {
-		return "", err
+		return nil, fmt.Errorf("generated code is invalid: %s", err)
 	}
-	structContent += code
-
-	return structContent, nil
+	return data, nil
 }
 
-func generateGenericPacket(p *PacketLayout) (s string, err error) {
-	structContent, err := generateStructData(p)
-	if err != nil {
-		return "", err
-	}
-
-	code, err := generateNewPacketFunc(p)
-	if err != nil {
-		return "", err
-	}
-	structContent += "\n" + code
-
-	code, err = generatePacketLengthCode(p)
-	if err != nil {
-		return "", err
-	}
-	structContent += "\n" + code
-
-	code, err = generatePacketAdjustLengthCode(p)
-	if err != nil {
-		return "", err
-	}
-	structContent += "\n" + code
-
-	code, err = generateReadCode(p)
-	if err != nil {
-		return "", err
-	}
-	structContent += "\n" + code
-
-	code, err = generateWriteCode(p)
-	if err != nil {
-		return "", err
+// goType returns the declared Go type of a field.
+func goType(f *FieldLayout) string {
+	switch f.kind {
+	case ArrayFieldKind:
+		return fmt.Sprintf("[%d]%s", f.arrayLen, f.fieldType)
+	case SliceFieldKind:
+		return "[]" + f.fieldType
 	}
-	structContent += "\n" + code
-
-	return structContent, nil
+	return f.fieldType
 }
 
-func generateVLFPacket(p *PacketLayout) (s string, err error) {
-	structContent, err := generateStructData(p)
-	if err != nil {
-		return "", err
-	}
-
-	code, err := generateNewPacketFunc(p)
-	if err != nil {
-		return "", err
+// goStreamType returns the suffix of the stream method which reads or writes the kind.
+func goStreamType(k FieldKind) string {
+	switch k.Size() {
+	case 1:
+		return "Byte"
+	case 2:
+		return "Uint16"
+	case 4:
+		return "Uint32"
 	}
-	structContent += code
-
-	code, err = generatePacketLengthCode(p)
-	if err != nil {
-		return "", err
-	}
-	structContent += "\n" + code
-
-	code, err = generatePacketAdjustLengthCode(p)
-	if err != nil {
-		return "", err
-	}
-	structContent += "\n" + code
+	return "Uint64"
+}
 
-	code, err = generateReadCode(p)
-	if err != nil {
-		return "", err
+// goPatternVars returns the template function naming the variable of the compiled pattern
+// of a field. The variables are numbered in definition order, since names joined from the
+// struct and field names could collide.
+func goPatternVars(schema *Schema) func(f *FieldLayout) string {
+	names := make(map[*FieldLayout]string)
+	for _, p := range schema.Packets {
+		for _, f := range p.fields {
+			if _, ok := f.annotations["pattern"]; ok {
+				names[f] = fmt.Sprintf("pattern%d", len(names))
+			}
+		}
 	}
-	structContent += "\n" + code
+	return func(f *FieldLayout) string { return names[f] }
+}
 
-	code, err = generateWriteCode(p)
-	if err != nil {
-		return "", err
+// goLimit writes a value of the generated Limits, a power of two as a shift.
+func goLimit(n int) string {
+	if n > 1 && n&(n-1) == 0 {
+		return fmt.Sprintf("1 << %d", bits.TrailingZeros(uint(n)))
 	}
-	structContent += "\n" + code
-
-	return structContent, nil
+	return strconv.Itoa(n)
 }
 
-func generatePacketAdjustLengthCode(p *PacketLayout) (s string, err error) {
-	if p.kind != StructKind {
-		s = fmt.Sprintf("func (s *%s) AdjustLength() { s.PacketHeader.Len = uint32(s.Length()) }", p.name)
-	} else {
-		s = fmt.Sprintf("func (s *%s) AdjustLength() {}", p.name)
-	}
-	return s, nil
+// goDefaultValue is a field initialized by New<Name>() and Reset().
+type goDefaultValue struct {
+	Name string
+	// Value is a Go expression, a composite literal for structs containing defaults.
+	Value string
 }
 
-func generatePacketLengthCode(p *PacketLayout) (s string, err error) {
-	code := fmt.Sprintf("func (s *%s) Length() int {\nvar totalLength int", p.name)
-	if p.kind != StructKind {
-		code += "\ntotalLength +=s.PacketHeader.Length()"
-	}
+// goDefaultValues returns the fields of p with a default annotation, and the struct
+// and struct array fields whose structs contain such fields.
+func goDefaultValues(schema *Schema, p *PacketLayout) []goDefaultValue {
+	var values []goDefaultValue
 	for _, f := range p.fields {
-		if v, ok := FieldKindLengthMap[f.kind]; ok {
-			code += "\ntotalLength += " + v
-		} else {
-			if f.kind == StructFieldKind {
-				code += fmt.Sprintf("\ntotalLength += s.%s.Length()", f.name)
-
-			} else if f.kind == SliceFieldKind {
-				code += fmt.Sprintf("\ntotalLength += 4")
-				if f.subElementKind == StructFieldKind {
-					code += fmt.Sprintf("\nfor i := 0; i < len(s.%s); i++ {\ntotalLength += s.%s[i].Length()\n}", f.name, f.name)
-				} else if f.subElementKind == StringFieldKind {
-					code += fmt.Sprintf("\nfor i := 0; i < len(s.%s); i++ {\ntotalLength += 4\ntotalLength += len(s.%s[i])\n}", f.name, f.name)
-				} else {
-					code += fmt.Sprintf("\ntotalLength += len(s.%s) * %s", f.name, FieldKindLengthMap[f.subElementKind])
-				}
-
-			} else if f.kind == ArrayFieldKind {
-				arrayIndex, err := getArrayTypeLength(f.field)
-				if err != nil {
-					return "", nil
-				}
-				if f.subElementKind == StructFieldKind {
-					code += fmt.Sprintf("\nfor i := 0; i < %d; i++ {\ntotalLength += s.%s[i].Length()\n}", arrayIndex, f.name)
-
-				} else if f.subElementKind == StringFieldKind {
-					code += fmt.Sprintf("\nfor i := 0; i < %d; i++ {\ntotalLength += 4\ntotalLength += len(s.%s[i])\n}", arrayIndex, f.name)
-				} else {
-					code += fmt.Sprintf("\ntotalLength += %d * %s", arrayIndex, FieldKindLengthMap[f.subElementKind])
-				}
-			} else if f.kind == StringFieldKind {
-				code += fmt.Sprintf("\ntotalLength += 4")
-				code += fmt.Sprintf("\ntotalLength += len(s.%s)", f.name)
-			}
+		if value := goDefaultValueOf(schema, f); len(value) != 0 {
+			values = append(values, goDefaultValue{Name: f.name, Value: value})
 		}
 	}
-	code += "\nreturn totalLength\n}"
-	return code, nil
+	return values
 }
 
-func generateArrayFieldReadCode(fieldKind FieldKind, fieldName, fieldTypeName string, slice bool) string {
-	code := "\n{\n"
-	if slice {
-		code += fmt.Sprintf("var size uint32\nif size, err = stream.ReadUint32(); err != nil {\nreturn err\n}\n")
-		code += fmt.Sprintf("s.%s = make([]%s, size)\n", fieldName, fieldTypeName)
+func goDefaultValueOf(schema *Schema, f *FieldLayout) string {
+	if value, ok := f.annotations["default"]; ok {
+		if f.kind == StringFieldKind {
+			return strconv.Quote(value)
+		}
+		return value
 	}
-
-	if fieldKind == ByteFieldKind || fieldKind == Int8FieldKind || fieldKind == Uint8FieldKind {
-		code += fmt.Sprintf("if buff, err := stream.ReadBuff(int(size)); err != nil { return err } else { s.%s = []%s(buff[:]) }", fieldName, fieldTypeName)
-		code += "\n}\n"
-		return code
+	if f.subElementKind != StructFieldKind || f.kind == SliceFieldKind {
+		return ""
 	}
-
-	code += fmt.Sprintf("for i := 0; i < len(s.%s); i++{\n", fieldName)
-	switch fieldKind {
-	case StructFieldKind:
-		{
-			code += fmt.Sprintf("if err = s.%s[i].Read(stream); err != nil {\nreturn err\n}\n", fieldName)
-		}
-	case StringFieldKind:
-		{
-			code += fmt.Sprintf("var elementSize uint32\n"+
-				"if elementSize, err = stream.ReadUint32(); err != nil{\nreturn err\n}\n"+
-				"var buff []byte\n"+
-				"if buff, err = stream.ReadBuff(int(elementSize)); err != nil {\nreturn err\n}\n"+
-				"s.%s[i] = string(buff)\n", fieldName)
-		}
-	case Int8FieldKind:
-		{
-			code += fmt.Sprintf("if val, err := stream.ReadByte(); err != nil { returne err } else { s.%s[i] = int8(val) }", fieldName)
-		}
-	case Int16FieldKind:
-		{
-			code += fmt.Sprintf("if val, err := stream.ReadUint16(); err != nil { return err } else { s.%s[i] = int16(val) }", fieldName)
-		}
-	case Int32FieldKind:
-		{
-			code += fmt.Sprintf("if val, err := stream.ReadUint32(); err != nil { return err } else { s.%s[i] = int32(val) }", fieldName)
-		}
-	case Int64FieldKind:
-		{
-			code += fmt.Sprintf("if val, err := stream.ReadUint64(); err != nil { return err } else { s.%s[i] = int64(val) }", fieldName)
-		}
-	case Uint8FieldKind:
-		{
-			code += fmt.Sprintf("if val, err := stream.ReadByte(); err != nil { returne err } else { s.%s[i] = uint8(val) }", fieldName)
-		}
-	case Uint16FieldKind:
-		{
-			code += fmt.Sprintf("if s.%s[i], err = stream.ReadUint16(); err != nil { return err } ", fieldName)
-		}
-	case Uint32FieldKind:
-		{
-			code += fmt.Sprintf("if s.%s[i], err = stream.ReadUint32(); err != nil { return err } ", fieldName)
-		}
-	case Uint64FieldKind:
-		{
-			code += fmt.Sprintf("if s.%s[i], err = stream.ReadUint64(); err != nil { return err } ", fieldName)
+	p := schema.Lookup(f.fieldType)
+	if p == nil {
+		return ""
+	}
+	var members []string
+	for _, value := range goDefaultValues(schema, p) {
+		members = append(members, value.Name+": "+value.Value)
+	}
+	if len(members) == 0 {
+		return ""
+	}
+	elem := "{" + strings.Join(members, ", ") + "}"
+	if f.kind == ArrayFieldKind {
+		elems := make([]string, f.arrayLen)
+		for i := range elems {
+			elems[i] = elem
 		}
-
+		return fmt.Sprintf("[%d]%s{%s}", f.arrayLen, p.name, strings.Join(elems, ", "))
 	}
-	code += "\n}\n}\n"
-	return code
+	return p.name + elem
 }
 
-func generateReadCode(p *PacketLayout) (s string, err error) {
-	code := fmt.Sprintf("func (s *%s) Read(stream ReadStream) error {\nvar err error\n", p.name)
-	for _, f := range p.fields {
-		switch f.kind {
-		case StructFieldKind:
-			{
-				code += fmt.Sprintf("\nif err = s.%s.Read(stream); err != nil { return err }", f.name)
-			}
-		case SliceFieldKind:
-			{
-				code += generateArrayFieldReadCode(f.subElementKind, f.name, f.fieldType, true)
-			}
-		case ArrayFieldKind:
-			{
-				code += generateArrayFieldReadCode(f.subElementKind, f.name, f.fieldType, false)
-			}
-		case StringFieldKind:
-			{
-				code += fmt.Sprintf("{\nvar size uint32"+
-					"\nif size, err = stream.ReadUint32(); err != nil { return err }"+
-					"\nvar buff []byte"+
-					"\nif buff, err = stream.ReadBuff(int(size)); err != nil { return err }"+
-					"\ns.%s = string(buff)\n}\n", f.name)
-			}
-		case ByteFieldKind:
-			{
-				code += fmt.Sprintf("if s.%s, err = stream.ReadByte(); err != nil { return err }\n", f.name)
-			}
-		case Int8FieldKind:
-			{
-				code += fmt.Sprintf("if val, err := stream.ReadByte(); err != nil { return err } else { s.%s = int8(val) }\n", f.name)
-			}
-		case Int16FieldKind:
-			{
-				code += fmt.Sprintf("if val, err := stream.ReadUint16(); err != nil { return err } else { s.%s = int16(val) }\n", f.name)
-			}
-		case Int32FieldKind:
-			{
-				code += fmt.Sprintf("if val, err := stream.ReadUint32(); err != nil { return err } else { s.%s = int32(val) }\n", f.name)
-			}
-		case Int64FieldKind:
-			{
-				code += fmt.Sprintf("if val, err := stream.ReadUint64(); err != nil { return err } else { s.%s = int64(val) }\n", f.name)
-			}
-		case Uint8FieldKind:
-			{
-				code += fmt.Sprintf("if s.%s, err = stream.ReadByte(); err != nil { return err }\n ", f.name)
-			}
-		case Uint16FieldKind:
-			{
-				code += fmt.Sprintf("if s.%s, err = stream.ReadUint16(); err != nil { return err }\n ", f.name)
-			}
-		case Uint32FieldKind:
-			{
-				code += fmt.Sprintf("if s.%s, err = stream.ReadUint32(); err != nil { return err }\n ", f.name)
-			}
-		case Uint64FieldKind:
-			{
-				code += fmt.Sprintf("if s.%s, err = stream.ReadUint64(); err != nil { return err }\n ", f.name)
+// goUsesAnnotation reports whether a field of the schema has the annotation key.
+func goUsesAnnotation(schema *Schema, key string) bool {
+	for _, p := range schema.Packets {
+		for _, f := range p.fields {
+			if _, ok := f.annotations[key]; ok {
+				return true
 			}
 		}
 	}
-	code += "\nreturn nil\n}"
-	return code, nil
+	return false
 }
 
-func generateArrayFieldWriteCode(fieldKind FieldKind, fieldName string, slice bool) string {
-	code := "\n{\n"
-	if slice {
-		code += fmt.Sprintf("if err = stream.WriteUint32(uint32(len(s.%s))); err != nil { return err }\n", fieldName)
-	}
-
-	if fieldKind == ByteFieldKind {
-		code += fmt.Sprintf("if err = stream.WriteBuff(s.%s); err != nil { return err }", fieldName)
-		code += "\n}\n"
-		return code
+// goStreamCast returns the type a signed value must be converted to before writing.
+func goStreamCast(k FieldKind) string {
+	if k.Size() == 1 {
+		return "byte"
 	}
-
-	code += fmt.Sprintf("for i := 0; i < len(s.%s); i++{\n", fieldName)
-	switch fieldKind {
-	case StructFieldKind:
-		{
-			code += fmt.Sprintf("if err = s.%s[i].Write(stream); err != nil { return err }", fieldName)
-		}
-	case StringFieldKind:
-		{
-			code += fmt.Sprintf("if err = stream.WriteUint32(uint32(len(s.%s[i]))); err != nil { return err }\n", fieldName)
-			code += fmt.Sprintf("if err = stream.WriteBuff([]byte(s.%s[i])); err != nil { return err }", fieldName)
-		}
-	case Int8FieldKind:
-		{
-			code += fmt.Sprintf("if err = stream.WriteByte(byte(s.%s[i])); err != nil { return err } ", fieldName)
-		}
-	case Int16FieldKind:
-		{
-			code += fmt.Sprintf("if err = stream.WriteUint16(uint16(s.%s[i])); err != nil { return err }", fieldName)
-		}
-	case Int32FieldKind:
-		{
-			code += fmt.Sprintf("if err = stream.WriteUint32(uint32(s.%s[i])); err != nil { return err }", fieldName)
-		}
-	case Int64FieldKind:
-		{
-			code += fmt.Sprintf("if err = stream.WriteUint64(uint64(s.%s[i])); err != nil { return err } ", fieldName)
-		}
-	case Uint8FieldKind:
-		{
-			code += fmt.Sprintf("if err = stream.WriteByte(s.%s[i]); err != nil { return err } ", fieldName)
-		}
-	case Uint16FieldKind:
-		{
-			code += fmt.Sprintf("if err = stream.WriteUint16(s.%s[i]); err != nil { return err } ", fieldName)
-		}
-	case Uint32FieldKind:
-		{
-			code += fmt.Sprintf("if err = stream.WriteUint32(s.%s[i]); err != nil { return err } ", fieldName)
-		}
-	case Uint64FieldKind:
-		{
-			code += fmt.Sprintf("if err = stream.WriteUint64(s.%s[i]); err != nil { return err } ", fieldName)
-		}
-	}
-	code += "\n}\n}\n"
-	return code
+	return "u" + k.String()
 }
 
-func generateWriteCode(p *PacketLayout) (s string, err error) {
-	code := fmt.Sprintf("func (s *%s) Write(stream WriteStream) error {\nvar err error\n", p.name)
-	if p.kind != StructKind {
-		code += "if err = s.PacketHeader.Write(stream); err != nil { return err }\n"
-	}
-	for _, f := range p.fields {
-		switch f.kind {
-		case StructFieldKind:
-			{
-				code += fmt.Sprintf("if err = s.%s.Write(stream); err != nil { return err }", f.name)
-			}
-		case SliceFieldKind:
-			{
-				code += generateArrayFieldWriteCode(f.subElementKind, f.name, true)
-			}
-		case ArrayFieldKind:
-			{
-				code += generateArrayFieldWriteCode(f.subElementKind, f.name, false)
-			}
-		case StringFieldKind:
-			{
-				code += fmt.Sprintf("\nif err = stream.WriteUint32(uint32(len(s.%s))); err != nil { return err }"+
-					"\nif err = stream.WriteBuff([]byte(s.%s)); err != nil { return err }", f.name, f.name)
-			}
-		case ByteFieldKind:
-			{
-				code += fmt.Sprintf("\nif err = stream.WriteByte(s.%s); err != nil { return err }", f.name)
-			}
-		case Int8FieldKind:
-			{
-				code += fmt.Sprintf("\nif err = stream.WriteByte(byte(s.%s)); err != nil { return err }", f.name)
-			}
-		case Int16FieldKind:
-			{
-				code += fmt.Sprintf("\nif err = stream.WriteUint16(uint16(s.%s)); err != nil { return err }", f.name)
-			}
-		case Int32FieldKind:
-			{
-				code += fmt.Sprintf("\nif err = stream.WriteUint32(uint32(s.%s)); err != nil { return err }", f.name)
-			}
-		case Int64FieldKind:
-			{
-				code += fmt.Sprintf("\nif err = stream.WriteUint64(uint64(s.%s)); err != nil { return err }", f.name)
-			}
-		case Uint8FieldKind:
-			{
-				code += fmt.Sprintf("\nif err = stream.WriteByte(byte(s.%s)); err != nil { return err } ", f.name)
-			}
-		case Uint16FieldKind:
-			{
-				code += fmt.Sprintf("\nif err = stream.WriteUint16(s.%s); err != nil { return err } ", f.name)
-			}
-		case Uint32FieldKind:
-			{
-				code += fmt.Sprintf("\nif err = stream.WriteUint32(s.%s); err != nil { return err } ", f.name)
-			}
-		case Uint64FieldKind:
-			{
-				code += fmt.Sprintf("\nif err = stream.WriteUint64(s.%s); err != nil { return err } ", f.name)
-			}
-
-		}
-	}
-	code += "\nreturn nil\n}"
-	return code, nil
+// goFieldDescriptor is a field entry of the descriptor table, Offset and Size are -1 if unknown.
+type goFieldDescriptor struct {
+	*FieldLayout
+	Offset int
+	Size   int
 }
 
-func generateNewPacketFunc(p *PacketLayout) (s string, err error) {
-	return fmt.Sprintf("\nfunc New%s() *%s { return &%s{\nPacketHeader:PacketHeader{\nPacketType:%s,\n},\n}\n}", p.name, p.name, p.name, strings.ToUpper(p.idname)), nil
+func (f *goFieldDescriptor) IsList() bool {
+	return f.kind == SliceFieldKind || f.kind == ArrayFieldKind
 }
 
-func generatePacketFactory(packets []*PacketLayout) (s string, err error) {
-	code := `
-	type PacketCacher interface {
-		Get(id uint32, header *PacketHeader) Packet
-		Put(id uint32, packet Packet)
-	}
-	
-	type PacketFactory struct {
-		Cacher PacketCacher
+// goDescriptorFields computes the offsets and sizes of the fields of p, like the protocol documentation.
+func goDescriptorFields(schema *Schema, p *PacketLayout) []*goFieldDescriptor {
+	offset := 0
+	if p.IsPacket() {
+		offset = PacketHeaderSize
 	}
-	
-	func NewPacketFactory(cacher PacketCacher) *PacketFactory {
-		return &PacketFactory{
-			Cacher: cacher,
-		}
-	}
-	
-	func (p *PacketFactory) CreatePacket(stream ReadStream) (newPacket Packet, err error) {
-		var header PacketHeader
-		if err = header.Read(stream); err != nil {
-			return nil, err
-		}
-		if p.Cacher != nil {
-			newPacket = p.Cacher.Get(header.PacketType, &header)
+	fields := make([]*goFieldDescriptor, 0, len(p.fields))
+	for _, f := range p.fields {
+		field := &goFieldDescriptor{FieldLayout: f, Offset: offset, Size: -1}
+		if size, ok := docFixedSize(schema, f); ok {
+			field.Size = size
 		}
-		if newPacket == nil {
-			switch header.PacketType {
-	`
-	for _, p := range packets {
-		if p.kind == StructKind {
-			continue
+		if offset >= 0 && field.Size >= 0 {
+			offset += field.Size
+		} else {
+			offset = -1
 		}
-		code += fmt.Sprintf("case %s:\n        {\n           newPacket = &%s{PacketHeader:header}\n        }\n", strings.ToUpper(p.idname), p.name)
+		fields = append(fields, field)
 	}
-	code += `
-				default:
-					{
-						return nil, ErrUnknownPacket
-					}
-				}
-			}
-			if err = newPacket.Read(stream); err != nil {
-				return nil, err
-			}
-			return newPacket, nil
-		}`
-	return code, nil
+	return fields
 }
@@ -0,0 +1,277 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+func init() {
+	RegisterBackend("jsonschema", jsonSchemaBackend{})
+}
+
+// jsonSchemaBackend exports the JSON form of the packets as JSON Schema (draft 2020-12).
+// Packets are defined under their ID name with a "type" property holding it, structs under
+// their type name. Byte slices are base64 strings, like encoding/json marshals them.
+// With the option "openapi" the definitions are wrapped into an OpenAPI 3.1 document,
+// "version" sets its info version.
+type jsonSchemaBackend struct{}
+
+func (jsonSchemaBackend) Generate(schema *Schema, opts Options) (map[string][]byte, error) {
+	ref := "#/$defs/"
+	if opts.Bool("openapi") {
+		ref = "#/components/schemas/"
+	}
+	t, err := loadTemplates("jsonschema", opts, template.FuncMap{
+		"json": func(v interface{}) (string, error) {
+			data, err := json.Marshal(v)
+			return string(data), err
+		},
+		"ref":          func(name string) string { return strconv.Quote(ref + name) },
+		"fieldSchema":  func(f *FieldLayout) string { return jsonSchemaField(f, ref) },
+		"uint32Schema": func() string { return jsonSchemaValue(Uint32FieldKind, "", ref).String() },
+	})
+	if err != nil {
+		return nil, err
+	}
+	code, err := executeTemplate(t, "document", &templateData{Schema: schema, Options: opts})
+	if err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	if err = json.Indent(&out, bytes.TrimSpace(code), "", "  "); err != nil {
+		return nil, fmt.Errorf("generated schema is invalid: %s", err)
+	}
+	out.WriteByte('\n')
+	return map[string][]byte{schema.PackageName + ".schema.json": out.Bytes()}, nil
+}
+
+// jsonRaw is a value which is written as it is, like a number too large for float64.
+type jsonRaw string
+
+// jsonObject is a JSON object which keeps the order of its members, the values are raw JSON.
+type jsonObject []struct{ key, value string }
+
+func (o *jsonObject) set(key string, value interface{}) {
+	var raw string
+	switch v := value.(type) {
+	case string:
+		raw = strconv.Quote(v)
+	case jsonRaw:
+		raw = string(v)
+	case jsonObject:
+		raw = v.String()
+	default:
+		raw = fmt.Sprint(v)
+	}
+	*o = append(*o, struct{ key, value string }{key, raw})
+}
+
+func (o jsonObject) String() string {
+	members := make([]string, len(o))
+	for i, m := range o {
+		members[i] = strconv.Quote(m.key) + ":" + m.value
+	}
+	return "{" + strings.Join(members, ",") + "}"
+}
+
+var jsonIntRanges = map[FieldKind][2]jsonRaw{
+	ByteFieldKind:   {"0", "255"},
+	Uint8FieldKind:  {"0", "255"},
+	Uint16FieldKind: {"0", "65535"},
+	Uint32FieldKind: {"0", "4294967295"},
+	Uint64FieldKind: {"0", "18446744073709551615"},
+	Int8FieldKind:   {"-128", "127"},
+	Int16FieldKind:  {"-32768", "32767"},
+	Int32FieldKind:  {"-2147483648", "2147483647"},
+	Int64FieldKind:  {"-9223372036854775808", "9223372036854775807"},
+}
+
+// jsonInt converts an annotation bound, which may be hexadecimal, into a JSON number.
+func jsonInt(v string) jsonRaw {
+	if n, err := strconv.ParseInt(v, 0, 64); err == nil {
+		return jsonRaw(strconv.FormatInt(n, 10))
+	}
+	n, _ := strconv.ParseUint(v, 0, 64)
+	return jsonRaw(strconv.FormatUint(n, 10))
+}
+
+// jsonByteBounds parses the length bounds of a string or byte slice, hi is -1 without max.
+func jsonByteBounds(min string, hasMin bool, max string, hasMax bool) (lo, hi int64) {
+	hi = -1
+	if hasMin {
+		lo, _ = strconv.ParseInt(min, 0, 64)
+	}
+	if hasMax {
+		hi, _ = strconv.ParseInt(max, 0, 64)
+	}
+	return lo, hi
+}
+
+// jsonByteRange describes the length bounds of jsonByteBounds.
+func jsonByteRange(lo, hi int64) string {
+	nBytes := func(n int64) string {
+		if n == 1 {
+			return "1 byte"
+		}
+		return fmt.Sprintf("%d bytes", n)
+	}
+	switch {
+	case lo == hi:
+		return "exactly " + nBytes(lo)
+	case hi < 0:
+		return "at least " + nBytes(lo)
+	case lo == 0:
+		return "at most " + nBytes(hi)
+	}
+	return fmt.Sprintf("%d to %s", lo, nBytes(hi))
+}
+
+// base64Pattern matches the padded base64 text encoding/json writes for a byte slice.
+const base64Pattern = "^(?:[A-Za-z0-9+/]{4})*(?:[A-Za-z0-9+/]{2}==|[A-Za-z0-9+/]{3}=)?$"
+
+// base64LengthPattern returns a pattern matching the padded base64 text of lo to hi bytes,
+// hi is -1 if there is no upper bound. It fails if a repeat count exceeds 1000, the limit
+// of many regular expression engines.
+func base64LengthPattern(lo, hi int64) (string, bool) {
+	tails := []string{"", "[A-Za-z0-9+/]{2}==", "[A-Za-z0-9+/]{3}="}
+	var alternatives []string
+	// n bytes are n/3 groups of 4 characters followed by the tail of the n%3 bytes left.
+	for r, tail := range tails {
+		min, max := int64(0), int64(-1)
+		if lo > int64(r) {
+			min = (lo - int64(r) + 2) / 3
+		}
+		if hi >= 0 {
+			if hi < int64(r) {
+				continue
+			}
+			if max = (hi - int64(r)) / 3; max < min {
+				continue
+			}
+		}
+		if min > 1000 || max > 1000 {
+			return "", false
+		}
+		count := fmt.Sprintf("{%d,}", min)
+		if max == min {
+			count = fmt.Sprintf("{%d}", min)
+		} else if max >= 0 {
+			count = fmt.Sprintf("{%d,%d}", min, max)
+		}
+		alternatives = append(alternatives, "(?:[A-Za-z0-9+/]{4})"+count+tail)
+	}
+	return "^(?:" + strings.Join(alternatives, "|") + ")$", true
+}
+
+// jsonSchemaValue returns the schema of a single value of kind k.
+func jsonSchemaValue(k FieldKind, typeName, ref string) jsonObject {
+	var o jsonObject
+	switch k {
+	case StringFieldKind:
+		o.set("type", "string")
+	case StructFieldKind:
+		o.set("$ref", ref+typeName)
+	default:
+		o.set("type", "integer")
+		o.set("minimum", jsonIntRanges[k][0])
+		o.set("maximum", jsonIntRanges[k][1])
+	}
+	return o
+}
+
+// jsonSchemaField returns the schema of a field, the min, max and len annotations become the
+// bounds of its length, element count or value. They count the bytes of a string on the
+// wire, while minLength and maxLength count characters, so a string gets the character
+// bounds every valid value meets and a $comment with the byte bounds. A byte slice gets
+// a pattern matching the base64 text of the allowed lengths.
+// A required string or slice has a length of at least one, a required integer is not 0.
+// The default annotation becomes the default of the field.
+func jsonSchemaField(f *FieldLayout, ref string) string {
+	var o jsonObject
+	min, hasMin := f.Annotation("min")
+	max, hasMax := f.Annotation("max")
+	if n, ok := f.Annotation("len"); ok {
+		min, hasMin, max, hasMax = n, true, n, true
+	}
+	_, required := f.Annotation("required")
+	if required && !hasMin && (f.kind == StringFieldKind || f.kind == SliceFieldKind) {
+		min, hasMin = "1", true
+	}
+	isByte := f.subElementKind == ByteFieldKind || f.subElementKind == Uint8FieldKind
+	switch {
+	case f.kind == SliceFieldKind && isByte:
+		o.set("type", "string")
+		o.set("contentEncoding", "base64")
+		if hasMin || hasMax {
+			lo, hi := jsonByteBounds(min, hasMin, max, hasMax)
+			if pattern, ok := base64LengthPattern(lo, hi); ok {
+				o.set("pattern", pattern)
+			} else {
+				// The repeat counts are too large for a pattern, bound the length of the text instead.
+				o.set("pattern", base64Pattern)
+				o.set("minLength", (lo+2)/3*4)
+				if hi >= 0 {
+					o.set("maxLength", (hi+2)/3*4)
+				}
+			}
+			o.set("$comment", "decoded data of "+jsonByteRange(lo, hi))
+		}
+	case f.kind == SliceFieldKind || f.kind == ArrayFieldKind:
+		o.set("type", "array")
+		o.set("items", jsonSchemaValue(f.subElementKind, f.fieldType, ref))
+		if f.kind == ArrayFieldKind {
+			o.set("minItems", f.arrayLen)
+			o.set("maxItems", f.arrayLen)
+		}
+		if hasMin {
+			o.set("minItems", jsonInt(min))
+		}
+		if hasMax {
+			o.set("maxItems", jsonInt(max))
+		}
+	case f.kind == StringFieldKind:
+		o = jsonSchemaValue(f.kind, f.fieldType, ref)
+		lo, hi := jsonByteBounds(min, hasMin, max, hasMax)
+		if hasMin {
+			// A character takes at most 4 bytes in UTF-8.
+			o.set("minLength", (lo+3)/4)
+		}
+		if hasMax {
+			o.set("maxLength", hi)
+		}
+		if hasMin || hasMax {
+			o.set("$comment", "UTF-8 encoding of "+jsonByteRange(lo, hi))
+		}
+		if pattern, ok := f.Annotation("pattern"); ok {
+			o.set("pattern", pattern)
+		}
+	default:
+		o = jsonSchemaValue(f.kind, f.fieldType, ref)
+		for i := range o {
+			if o[i].key == "minimum" && hasMin {
+				o[i].value = string(jsonInt(min))
+			}
+			if o[i].key == "maximum" && hasMax {
+				o[i].value = string(jsonInt(max))
+			}
+		}
+		if required && f.kind != StructFieldKind && f.kind != ArrayFieldKind {
+			o.set("not", jsonObject{{"const", "0"}})
+		}
+	}
+	if v, ok := f.Annotation("default"); ok {
+		if f.kind == StringFieldKind {
+			o.set("default", v)
+		} else {
+			o.set("default", jsonInt(v))
+		}
+	}
+	if len(f.doc) != 0 {
+		o.set("description", f.doc)
+	}
+	return o.String()
+}
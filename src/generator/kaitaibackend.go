@@ -0,0 +1,40 @@
+package generator
+
+import "text/template"
+
+func init() {
+	RegisterBackend("kaitai", kaitaiBackend{})
+}
+
+// kaitaiBackend exports the schema as a Kaitai Struct .ksy file. The top level type is a
+// stream of packets, every packet is the header followed by a body which switches on the
+// packet type. Strings and slices are preceded by their u4 length fields.
+type kaitaiBackend struct{}
+
+func (kaitaiBackend) Generate(schema *Schema, opts Options) (map[string][]byte, error) {
+	endian, err := opts.Endian()
+	if err != nil {
+		return nil, err
+	}
+	t, err := loadTemplates("kaitai", opts, template.FuncMap{
+		"ksyID":  snakeCase,
+		"ksyInt": ksyInt,
+		"isByte": func(k FieldKind) bool { return k == ByteFieldKind || k == Uint8FieldKind },
+	})
+	if err != nil {
+		return nil, err
+	}
+	code, err := executeTemplate(t, "ksy", &templateData{Schema: schema, Options: opts, Endian: endian})
+	if err != nil {
+		return nil, err
+	}
+	return map[string][]byte{snakeCase(schema.PackageName) + ".ksy": code}, nil
+}
+
+// ksyInt returns the Kaitai integer type of a fixed size kind, u4 for uint32 and s2 for int16.
+func ksyInt(k FieldKind) string {
+	if k.IsSigned() {
+		return "s" + string(rune('0'+k.Size()))
+	}
+	return "u" + string(rune('0'+k.Size()))
+}
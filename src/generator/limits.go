@@ -0,0 +1,88 @@
+package generator
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// DecodeLimits bounds the lengths a reader accepts from the stream before it allocates,
+// 0 disables a limit. The go backend generates the same type, its Limits variable starts
+// with DefaultDecodeLimits; the dynamic package checks them with the methods below.
+type DecodeLimits struct {
+	// MaxElements limits the element count of slices other than byte slices.
+	MaxElements int
+	// MaxStringBytes limits the length of strings and byte slices.
+	MaxStringBytes int
+	// MaxTotalBytes limits the Len in the header of a packet.
+	MaxTotalBytes int
+}
+
+var DefaultDecodeLimits = DecodeLimits{MaxElements: 1 << 20, MaxStringBytes: 1 << 24, MaxTotalBytes: 1 << 26}
+
+// LimitError reports a length read from the stream which exceeds a limit.
+type LimitError struct {
+	// Field is the field name qualified by its packet or struct, e.g. LoginRequest.UserName.
+	Field  string
+	Length uint32
+	// Limit names the exceeded limit: max for the annotation of the field, a field of
+	// DecodeLimits, or Left if the data left can't hold the elements.
+	Limit string
+	Max   int
+}
+
+func (e *LimitError) Error() string {
+	if e.Limit == "Left" {
+		return fmt.Sprintf("%s: %d elements need more than the %d bytes left", e.Field, e.Length, e.Max)
+	}
+	return fmt.Sprintf("%s: length %d exceeds %s %d", e.Field, e.Length, e.Limit, e.Max)
+}
+
+// CheckCount checks the element count of a slice before it is allocated. max is the annotation
+// of the field, -1 if there is none, minSize is the least wire size of an element and left
+// the bytes left in the stream.
+func (l DecodeLimits) CheckCount(field string, count uint32, max, minSize, left int) error {
+	switch {
+	case max >= 0 && uint64(count) > uint64(max):
+		return &LimitError{Field: field, Length: count, Limit: "max", Max: max}
+	case l.MaxElements > 0 && uint64(count) > uint64(l.MaxElements):
+		return &LimitError{Field: field, Length: count, Limit: "MaxElements", Max: l.MaxElements}
+	case uint64(count)*uint64(minSize) > uint64(left):
+		return &LimitError{Field: field, Length: count, Limit: "Left", Max: left}
+	}
+	return nil
+}
+
+// CheckBytes checks the length of a string or byte slice before it is read,
+// max is the annotation of the field, -1 if there is none.
+func (l DecodeLimits) CheckBytes(field string, length uint32, max int) error {
+	switch {
+	case max >= 0 && uint64(length) > uint64(max):
+		return &LimitError{Field: field, Length: length, Limit: "max", Max: max}
+	case l.MaxStringBytes > 0 && uint64(length) > uint64(l.MaxStringBytes):
+		return &LimitError{Field: field, Length: length, Limit: "MaxStringBytes", Max: l.MaxStringBytes}
+	}
+	return nil
+}
+
+// CheckTotal checks the Len of a packet header before the packet is read.
+func (l DecodeLimits) CheckTotal(length uint32) error {
+	if l.MaxTotalBytes > 0 && uint64(length) > uint64(l.MaxTotalBytes) {
+		return &LimitError{Field: "PacketHeader.Len", Length: length, Limit: "MaxTotalBytes", Max: l.MaxTotalBytes}
+	}
+	return nil
+}
+
+// LengthMax returns the max annotation of a string or slice field, which limits its length
+// when it is read. It is -1 if there is none or it exceeds the int range of every platform.
+func (f *FieldLayout) LengthMax() int {
+	value, ok := f.annotations["max"]
+	if !ok || (f.kind != StringFieldKind && f.kind != SliceFieldKind) {
+		return -1
+	}
+	max, err := strconv.ParseInt(value, 0, 64)
+	if err != nil || max > math.MaxInt32 {
+		return -1
+	}
+	return int(max)
+}
@@ -0,0 +1,76 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+func init() {
+	RegisterBackend("lua", luaBackend{})
+}
+
+// luaBackend generates a Lua 5.3 module. Every packet and struct is described by a table
+// whose fields carry string.pack formats, a small interpreter in the module encodes and
+// decodes the values by these descriptions.
+type luaBackend struct{}
+
+func (luaBackend) Generate(schema *Schema, opts Options) (map[string][]byte, error) {
+	endian, err := opts.Endian()
+	if err != nil {
+		return nil, err
+	}
+	t, err := loadTemplates("lua", opts, template.FuncMap{
+		"luaField": luaField,
+		"minWireSize": func(p *PacketLayout) int {
+			return schema.MinWireSize(StructFieldKind, p.name)
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	code, err := executeTemplate(t, "module", &templateData{Schema: schema, Options: opts, Endian: endian})
+	if err != nil {
+		return nil, err
+	}
+	return map[string][]byte{strings.ToLower(schema.PackageName) + ".lua": code}, nil
+}
+
+var luaFormats = map[FieldKind]string{
+	ByteFieldKind:   "B",
+	Uint8FieldKind:  "B",
+	Uint16FieldKind: "I2",
+	Uint32FieldKind: "I4",
+	Uint64FieldKind: "I8",
+	Int8FieldKind:   "b",
+	Int16FieldKind:  "i2",
+	Int32FieldKind:  "i4",
+	Int64FieldKind:  "i8",
+	StringFieldKind: "s4",
+}
+
+// luaField returns the table literal describing a field. Scalars and strings have a format,
+// byte slices and byte arrays are packed as a single string, other slices have a count
+// format and arrays a fixed len, their elements have either a format or a struct type.
+func luaField(f *FieldLayout) string {
+	elem := fmt.Sprintf("format = %q", luaFormats[f.subElementKind])
+	if f.subElementKind == StructFieldKind {
+		elem = fmt.Sprintf("type = %q", f.fieldType)
+	}
+	isByte := f.subElementKind == ByteFieldKind || f.subElementKind == Uint8FieldKind
+	switch f.kind {
+	case SliceFieldKind:
+		if isByte {
+			return fmt.Sprintf("{ name = %q, kind = \"slice\", format = \"s4\" }", f.name)
+		}
+		return fmt.Sprintf("{ name = %q, kind = \"slice\", count = \"I4\", %s }", f.name, elem)
+	case ArrayFieldKind:
+		if isByte {
+			return fmt.Sprintf("{ name = %q, kind = \"array\", format = \"c%d\" }", f.name, f.arrayLen)
+		}
+		return fmt.Sprintf("{ name = %q, kind = \"array\", len = %d, %s }", f.name, f.arrayLen, elem)
+	case StructFieldKind:
+		return fmt.Sprintf("{ name = %q, kind = \"struct\", %s }", f.name, elem)
+	}
+	return fmt.Sprintf("{ name = %q, kind = %q, %s }", f.name, f.kind.String(), elem)
+}
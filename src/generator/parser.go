@@ -5,6 +5,8 @@ import (
 	"go/ast"
 	goparser "go/parser"
 	"go/token"
+	"reflect"
+	"regexp"
 	"strconv"
 	"strings"
 )
@@ -48,14 +50,190 @@ var FieldKindLengthMap = map[FieldKind]string{
 	Int64FieldKind:  "8",
 }
 
+// PacketHeaderFields lists the packet header fields in wire order, each of them is a uint32.
+var PacketHeaderFields = []string{"ID", "PacketType", "Len", "Version", "Ack", "Token"}
+
+// PacketHeaderSize is the wire size of the packet header.
+const PacketHeaderSize = 24
+
+// PacketHeaderLength is what the packet header counts for in Length() and Len. It has been 36
+// since the first version and peers depend on it, so Len covers PacketPadding reserved bytes
+// after the fields, which are written as zeros and ignored when read. A packet takes Len bytes.
+const PacketHeaderLength = 36
+
+// PacketPadding is the number of reserved bytes after the fields of a packet.
+const PacketPadding = PacketHeaderLength - PacketHeaderSize
+
+func (k PacketKind) String() string {
+	switch k {
+	case SimplePacketKind:
+		return "SimplePacket"
+	case VLFPacketKind:
+		return "VLFPacket"
+	case GenericPacketKind:
+		return "Packet"
+	case StructKind:
+		return "Struct"
+	}
+	return "Unknown"
+}
+
+var fieldKindNames = map[FieldKind]string{
+	SliceFieldKind:  "slice",
+	ArrayFieldKind:  "array",
+	StructFieldKind: "struct",
+	ByteFieldKind:   "byte",
+	Uint8FieldKind:  "uint8",
+	Uint16FieldKind: "uint16",
+	Uint32FieldKind: "uint32",
+	Uint64FieldKind: "uint64",
+	Int8FieldKind:   "int8",
+	Int16FieldKind:  "int16",
+	Int32FieldKind:  "int32",
+	Int64FieldKind:  "int64",
+	StringFieldKind: "string",
+}
+
+func (k FieldKind) String() string {
+	if name, ok := fieldKindNames[k]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// Size returns the wire size of a fixed size kind, or 0 if the kind has variable size.
+func (k FieldKind) Size() int {
+	if v, ok := FieldKindLengthMap[k]; ok {
+		size, _ := strconv.Atoi(v)
+		return size
+	}
+	return 0
+}
+
+func (k FieldKind) IsSigned() bool {
+	return k == Int8FieldKind || k == Int16FieldKind || k == Int32FieldKind || k == Int64FieldKind
+}
+
+// Schema is the parsed protocol which is handed to the backends.
+type Schema struct {
+	PackageName string
+	Packets     []*PacketLayout
+}
+
+// ParseSchema parses the protocol file and returns its schema.
+func ParseSchema(file string) (*Schema, error) {
+	return parseSchema(file, nil)
+}
+
+// ParseSchemaSource parses a protocol definition held in memory, errors name it protocol.go.
+func ParseSchemaSource(src []byte) (*Schema, error) {
+	return parseSchema("protocol.go", src)
+}
+
+// parseSchema parses src, or the file if src is nil, as go/parser.ParseFile does.
+func parseSchema(file string, src interface{}) (*Schema, error) {
+	parser, err := newProtoParser(file, src)
+	if err != nil {
+		return nil, err
+	}
+	if err = parser.Parse(); err != nil {
+		return nil, err
+	}
+	return parser.Schema(), nil
+}
+
+// Lookup finds a packet or struct by its type name.
+func (s *Schema) Lookup(name string) *PacketLayout {
+	for _, p := range s.Packets {
+		if p.name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// LookupID finds a packet by its packet type value.
+func (s *Schema) LookupID(id uint32) *PacketLayout {
+	for _, p := range s.Packets {
+		if p.kind != StructKind && uint32(p.id) == id {
+			return p
+		}
+	}
+	return nil
+}
+
+// LookupIDName finds a packet by its ID name. The name is compared exactly with IDName,
+// the upper case form MarshalJSON writes and PacketFromJSON accepts.
+func (s *Schema) LookupIDName(idname string) *PacketLayout {
+	for _, p := range s.Packets {
+		if p.kind != StructKind && p.IDName() == idname {
+			return p
+		}
+	}
+	return nil
+}
+
+// Sorted returns the layouts ordered so that every struct comes before the layouts
+// using it, which languages requiring declaration before use rely on.
+func (s *Schema) Sorted() []*PacketLayout {
+	sorted := make([]*PacketLayout, 0, len(s.Packets))
+	visited := make(map[*PacketLayout]bool)
+	var visit func(p *PacketLayout)
+	visit = func(p *PacketLayout) {
+		if visited[p] {
+			return
+		}
+		visited[p] = true
+		for _, f := range p.fields {
+			if f.subElementKind == StructFieldKind {
+				if dep := s.Lookup(f.fieldType); dep != nil {
+					visit(dep)
+				}
+			}
+		}
+		sorted = append(sorted, p)
+	}
+	for _, p := range s.Packets {
+		visit(p)
+	}
+	return sorted
+}
+
+// MinWireSize returns the least number of bytes a value of kind k takes on the wire,
+// typeName is the struct name of a struct kind.
+func (s *Schema) MinWireSize(k FieldKind, typeName string) int {
+	switch k {
+	case StringFieldKind, SliceFieldKind:
+		return 4
+	case StructFieldKind:
+		size := 0
+		if p := s.Lookup(typeName); p != nil {
+			for _, f := range p.fields {
+				switch f.kind {
+				case ArrayFieldKind:
+					size += f.arrayLen * s.MinWireSize(f.subElementKind, f.fieldType)
+				default:
+					size += s.MinWireSize(f.kind, f.fieldType)
+				}
+			}
+		}
+		return size
+	}
+	return k.Size()
+}
+
 type ProtoParser struct {
 	astFile *ast.File
 	packets []*PacketLayout
 }
 
 func NewProtoParser(file string) (*ProtoParser, error) {
+	return newProtoParser(file, nil)
+}
+
+func newProtoParser(file string, src interface{}) (*ProtoParser, error) {
 	fileSet := token.NewFileSet()
-	astFile, err := goparser.ParseFile(fileSet, file, nil, goparser.ParseComments)
+	astFile, err := goparser.ParseFile(fileSet, file, src, goparser.ParseComments)
 	if err != nil {
 		return nil, err
 	}
@@ -73,6 +251,7 @@ func (this *ProtoParser) Parse() error {
 			break
 		}
 		layout.name, layout.structType = this.parseStructInfo(decl)
+		layout.doc = parseDoc(decl.(*ast.GenDecl).Doc)
 		if err = layout.parseField(); err != nil {
 			break
 		}
@@ -81,6 +260,17 @@ func (this *ProtoParser) Parse() error {
 	return err
 }
 
+func (this *ProtoParser) Schema() *Schema {
+	packageName := "protocol"
+	if this.astFile.Name != nil {
+		packageName = this.astFile.Name.Name
+	}
+	return &Schema{
+		PackageName: packageName,
+		Packets:     this.packets,
+	}
+}
+
 func (this *ProtoParser) parsePacketType(decl ast.Decl) (kind PacketKind, IDName string, ID int, err error) {
 	kind = StructKind
 
@@ -117,6 +307,20 @@ func (this *ProtoParser) parsePacketType(decl ast.Decl) (kind PacketKind, IDName
 	return
 }
 
+// parseDoc returns the text of a doc comment without the packet annotation.
+func parseDoc(group *ast.CommentGroup) string {
+	if group == nil {
+		return ""
+	}
+	var lines []string
+	for _, line := range strings.Split(group.Text(), "\n") {
+		if !strings.HasPrefix(strings.TrimSpace(line), "@") {
+			lines = append(lines, line)
+		}
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
 func (this *ProtoParser) parseStructInfo(decl ast.Decl) (name string, structType *ast.StructType) {
 	genDecl := decl.(*ast.GenDecl)
 	for _, spec := range genDecl.Specs {
@@ -136,15 +340,63 @@ type PacketLayout struct {
 	name       string
 	id         int
 	idname     string
+	doc        string
 	fields     []*FieldLayout
 }
 
+func (p *PacketLayout) Name() string { return p.name }
+
+func (p *PacketLayout) Kind() PacketKind { return p.kind }
+
+func (p *PacketLayout) ID() uint32 { return uint32(p.id) }
+
+func (p *PacketLayout) IDName() string { return strings.ToUpper(p.idname) }
+
+func (p *PacketLayout) Fields() []*FieldLayout { return p.fields }
+
+// Doc returns the doc comment of the type, the packet annotation is left out.
+func (p *PacketLayout) Doc() string { return p.doc }
+
+// IsPacket reports whether the layout is a packet, which has a PacketHeader, rather than a plain struct.
+func (p *PacketLayout) IsPacket() bool { return p.kind != StructKind }
+
 type FieldLayout struct {
 	field          *ast.Field
 	kind           FieldKind
 	name           string
+	parent         string
 	subElementKind FieldKind
 	fieldType      string
+	arrayLen       int
+	doc            string
+	annotations    map[string]string
+}
+
+func (f *FieldLayout) Name() string { return f.name }
+
+// Path returns the field name qualified by its packet or struct, e.g. LoginRequest.UserName.
+func (f *FieldLayout) Path() string { return f.parent + "." + f.name }
+
+func (f *FieldLayout) Kind() FieldKind { return f.kind }
+
+// ElemKind returns the element kind of a slice or array field,
+// for other fields it is the kind of the field itself.
+func (f *FieldLayout) ElemKind() FieldKind { return f.subElementKind }
+
+// TypeName returns the element type name, it is the struct name for struct fields.
+func (f *FieldLayout) TypeName() string { return f.fieldType }
+
+// ArrayLen returns the length of an array field.
+func (f *FieldLayout) ArrayLen() int { return f.arrayLen }
+
+// Doc returns the comment above the field, or the one after it on the same line.
+func (f *FieldLayout) Doc() string { return f.doc }
+
+// Annotation returns the value of a key of the field's goproto tag, a key given without
+// a value is "true".
+func (f *FieldLayout) Annotation(key string) (string, bool) {
+	value, ok := f.annotations[key]
+	return value, ok
 }
 
 func (p *PacketLayout) parseField() error {
@@ -152,7 +404,7 @@ func (p *PacketLayout) parseField() error {
 		return nil
 	} else if p.kind == VLFPacketKind {
 		if p.structType.Fields.NumFields() == 0 {
-			return fmt.Errorf("VLFPacket Must have a slice field,", p.name)
+			return fmt.Errorf("VLFPacket Must have a slice field, name: %s", p.name)
 		}
 		field := p.structType.Fields.List[0]
 		fieldLayout, err := NewFieldLayout(field)
@@ -160,21 +412,23 @@ func (p *PacketLayout) parseField() error {
 			return err
 		}
 		if fieldLayout.kind != SliceFieldKind {
-			return fmt.Errorf("VLFPacket Must have a slice field,", p.name)
+			return fmt.Errorf("VLFPacket Must have a slice field, name: %s", p.name)
 		}
+		fieldLayout.parent = p.name
 		p.fields = append(p.fields, fieldLayout)
 
 	} else {
 		for index := 0; index < len(p.structType.Fields.List); index++ {
 			field := p.structType.Fields.List[index]
 			if len(field.Names) == 0 {
-				return fmt.Errorf("disallow anonymouse field except SimplePacketProperty, VLFPacketProperty, PacketProperty",
-					"name:", p.name, "pos:", p.structType.Pos())
+				return fmt.Errorf("disallow anonymouse field except SimplePacketProperty, VLFPacketProperty, PacketProperty, name: %s, pos: %d",
+					p.name, p.structType.Pos())
 			}
 			fieldLayout, err := NewFieldLayout(field)
 			if err != nil {
 				return err
 			}
+			fieldLayout.parent = p.name
 			p.fields = append(p.fields, fieldLayout)
 		}
 	}
@@ -186,15 +440,223 @@ func NewFieldLayout(field *ast.Field) (*FieldLayout, error) {
 	var fieldLayout FieldLayout
 	fieldLayout.name = field.Names[0].Name
 	fieldLayout.field = field
+	if fieldLayout.doc = parseDoc(field.Doc); len(fieldLayout.doc) == 0 {
+		fieldLayout.doc = parseDoc(field.Comment)
+	}
+	if field.Tag != nil {
+		tag, err := strconv.Unquote(field.Tag.Value)
+		if err != nil {
+			return nil, err
+		}
+		if fieldLayout.annotations, err = parseAnnotations(reflect.StructTag(tag).Get("goproto")); err != nil {
+			return nil, fmt.Errorf("field %s: %s", fieldLayout.name, err)
+		}
+	}
 	if err := fieldLayout.parseFieldKind(); err != nil {
 		return nil, err
 	}
 	if err := fieldLayout.parseFieldType(); err != nil {
 		return nil, err
 	}
+	if err := fieldLayout.checkAnnotations(); err != nil {
+		return nil, fmt.Errorf("field %s: %s", fieldLayout.name, err)
+	}
 	return &fieldLayout, nil
 }
 
+// annotationKeys are the keys allowed in a goproto tag.
+var annotationKeys = map[string]bool{
+	"min":      true,
+	"max":      true,
+	"len":      true,
+	"pattern":  true,
+	"required": true,
+	"default":  true,
+}
+
+// parseAnnotations parses the content of a goproto tag, which is a comma separated list of
+// key=value pairs, each key at most once. A value may be enclosed in single quotes to contain
+// commas, a quote inside is escaped by a backslash.
+func parseAnnotations(tag string) (map[string]string, error) {
+	annotations := make(map[string]string)
+	for len(tag) != 0 {
+		end := strings.IndexAny(tag, "=,")
+		if end < 0 {
+			end = len(tag)
+		}
+		key := strings.TrimSpace(tag[:end])
+		if !annotationKeys[key] {
+			return nil, fmt.Errorf("unknown annotation %q", key)
+		}
+		value := "true"
+		if end < len(tag) && tag[end] == '=' {
+			tag = strings.TrimLeft(tag[end+1:], " ")
+			if strings.HasPrefix(tag, "'") {
+				var b strings.Builder
+				i := 1
+				for ; i < len(tag) && tag[i] != '\''; i++ {
+					if tag[i] == '\\' && i+1 < len(tag) {
+						i++
+					}
+					b.WriteByte(tag[i])
+				}
+				if i >= len(tag) {
+					return nil, fmt.Errorf("annotation %s: missing closing quote", key)
+				}
+				value, tag = b.String(), tag[i+1:]
+				end = strings.IndexByte(tag, ',')
+				if end < 0 {
+					end = len(tag)
+				}
+				if len(strings.TrimSpace(tag[:end])) != 0 {
+					return nil, fmt.Errorf("annotation %s: unexpected %q after quoted value", key, tag[:end])
+				}
+			} else {
+				if end = strings.IndexByte(tag, ','); end < 0 {
+					end = len(tag)
+				}
+				value = strings.TrimSpace(tag[:end])
+			}
+		}
+		if _, ok := annotations[key]; ok {
+			return nil, fmt.Errorf("duplicate annotation %q", key)
+		}
+		annotations[key] = value
+		if end < len(tag) {
+			end++
+		}
+		tag = tag[end:]
+	}
+	return annotations, nil
+}
+
+// formatAnnotations is the inverse of parseAnnotations, it returns the content of a goproto
+// tag in the order of annotationOrder. Values are quoted if parseAnnotations would change them.
+func formatAnnotations(annotations map[string]string) string {
+	var parts []string
+	for _, key := range annotationOrder {
+		value, ok := annotations[key]
+		switch {
+		case !ok:
+			continue
+		case key == "required" && value == "true":
+			parts = append(parts, key)
+			continue
+		case strings.ContainsAny(value, ",'") || value != strings.TrimSpace(value):
+			value = "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(value) + "'"
+		}
+		parts = append(parts, key+"="+value)
+	}
+	return strings.Join(parts, ",")
+}
+
+// annotationOrder lists the annotation keys in the order they are checked and formatted.
+var annotationOrder = []string{"min", "max", "len", "pattern", "required", "default"}
+
+// checkAnnotations verifies the annotations fit the field. The bounds min and max limit
+// the length of strings, the element count of slices and the value of integers, len gives
+// the exact length or count, pattern is a regular expression a string must match and
+// required asks for a non-zero value. default is the initial value of a string or integer.
+func (f *FieldLayout) checkAnnotations() error {
+	isLength := f.kind == StringFieldKind || f.kind == SliceFieldKind
+	for _, key := range annotationOrder {
+		value, ok := f.annotations[key]
+		if !ok {
+			continue
+		}
+		switch {
+		case f.kind == StructFieldKind || f.kind == ArrayFieldKind:
+			return fmt.Errorf("%s is not allowed on %s fields", key, f.kind)
+		case key == "pattern":
+			if f.kind != StringFieldKind {
+				return fmt.Errorf("pattern is only allowed on string fields")
+			}
+			if _, err := regexp.Compile(value); err != nil {
+				return fmt.Errorf("invalid pattern: %s", err)
+			}
+		case key == "required":
+			if value != "true" {
+				return fmt.Errorf("required takes no value, got %q", value)
+			}
+		case key == "len" && !isLength:
+			return fmt.Errorf("len is only allowed on string and slice fields")
+		case key == "default" && f.kind == SliceFieldKind:
+			return fmt.Errorf("default is not allowed on slice fields")
+		case key == "default" && f.kind == StringFieldKind:
+			// any text is a string default
+		case isLength:
+			if _, err := strconv.ParseUint(value, 0, 32); err != nil {
+				return fmt.Errorf("%s must be a length, got %q", key, value)
+			}
+		case f.kind.IsSigned():
+			if _, err := strconv.ParseInt(value, 0, f.kind.Size()*8); err != nil {
+				return fmt.Errorf("%s must be an integer of type %s, got %q", key, f.kind, value)
+			}
+		default:
+			if _, err := strconv.ParseUint(value, 0, f.kind.Size()*8); err != nil {
+				return fmt.Errorf("%s must be an integer of type %s, got %q", key, f.kind, value)
+			}
+		}
+	}
+	return f.checkDefault()
+}
+
+// checkDefault verifies the default annotation meets the other annotations of the field,
+// which have been checked to fit it.
+func (f *FieldLayout) checkDefault() error {
+	value, ok := f.annotations["default"]
+	if !ok {
+		return nil
+	}
+	_, required := f.annotations["required"]
+	if f.kind == StringFieldKind {
+		n := uint64(len(value))
+		if exact, ok := f.annotations["len"]; ok {
+			if want, _ := strconv.ParseUint(exact, 0, 32); n != want {
+				return fmt.Errorf("default %q is %d bytes long, len requires %d", value, n, want)
+			}
+		}
+		if min, ok := f.annotations["min"]; ok {
+			if want, _ := strconv.ParseUint(min, 0, 32); n < want {
+				return fmt.Errorf("default %q is shorter than min %d", value, want)
+			}
+		}
+		if max, ok := f.annotations["max"]; ok {
+			if want, _ := strconv.ParseUint(max, 0, 32); n > want {
+				return fmt.Errorf("default %q is longer than max %d", value, want)
+			}
+		}
+		if pattern, ok := f.annotations["pattern"]; ok && !regexp.MustCompile(pattern).MatchString(value) {
+			return fmt.Errorf("default %q does not match pattern %q", value, pattern)
+		}
+		if required && n == 0 {
+			return fmt.Errorf("default is empty, but the field is required")
+		}
+		return nil
+	}
+	// less compares integers of the field's type
+	less := func(a, b string) bool {
+		if f.kind.IsSigned() {
+			x, _ := strconv.ParseInt(a, 0, 64)
+			y, _ := strconv.ParseInt(b, 0, 64)
+			return x < y
+		}
+		x, _ := strconv.ParseUint(a, 0, 64)
+		y, _ := strconv.ParseUint(b, 0, 64)
+		return x < y
+	}
+	if min, ok := f.annotations["min"]; ok && less(value, min) {
+		return fmt.Errorf("default %s is less than min %s", value, min)
+	}
+	if max, ok := f.annotations["max"]; ok && less(max, value) {
+		return fmt.Errorf("default %s is greater than max %s", value, max)
+	}
+	if required && !less(value, "0") && !less("0", value) {
+		return fmt.Errorf("default is 0, but the field is required")
+	}
+	return nil
+}
+
 func (f *FieldLayout) parseFieldKind() error {
 	switch t := f.field.Type.(type) {
 	case *ast.StructType:
@@ -207,6 +669,11 @@ func (f *FieldLayout) parseFieldKind() error {
 				f.kind = SliceFieldKind
 			} else {
 				f.kind = ArrayFieldKind
+				length, err := getArrayTypeLength(f.field)
+				if err != nil {
+					return err
+				}
+				f.arrayLen = length
 			}
 			return f.parseFieldType()
 		}
@@ -291,3 +758,13 @@ func parseNameByType(exp ast.Expr) string {
 	}
 	return "unknown"
 }
+
+func getArrayTypeLength(field *ast.Field) (i int, err error) {
+	if a, ok := field.Type.(*ast.ArrayType); ok {
+		if b, ok := a.Len.(*ast.BasicLit); ok {
+			return strconv.Atoi(b.Value)
+		}
+	}
+
+	return 0, fmt.Errorf("invalid field")
+}
@@ -0,0 +1,115 @@
+package generator
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseAnnotations(t *testing.T) {
+	tests := []struct {
+		name string
+		tag  string
+		want map[string]string
+		// err is a part of the expected error, the tag is valid if it is empty
+		err string
+	}{
+		{"empty", "", map[string]string{}, ""},
+		{"values", "min=1,max=0x20", map[string]string{"min": "1", "max": "0x20"}, ""},
+		{"flag", "required", map[string]string{"required": "true"}, ""},
+		{"spaces", " min = 1 , required ", map[string]string{"min": "1", "required": "true"}, ""},
+		{"trailing comma", "max=3,", map[string]string{"max": "3"}, ""},
+		{"empty value", "default=", map[string]string{"default": ""}, ""},
+		{"value with spaces", "default=a b", map[string]string{"default": "a b"}, ""},
+		{"quoted comma", "pattern='^[a,b]{1,3}$',max=3", map[string]string{"pattern": "^[a,b]{1,3}$", "max": "3"}, ""},
+		{"quoted spaces", "default=' a '", map[string]string{"default": " a "}, ""},
+		{"escaped quote", `default='it\'s'`, map[string]string{"default": "it's"}, ""},
+		{"escaped backslash", `default='a\\'`, map[string]string{"default": `a\`}, ""},
+		{"quote inside plain value", "default=it's", map[string]string{"default": "it's"}, ""},
+		{"unknown key", "size=3", nil, `unknown annotation "size"`},
+		{"empty key", "max=3,,min=1", nil, `unknown annotation ""`},
+		{"duplicate key", "max=3,max=4", nil, `duplicate annotation "max"`},
+		{"duplicate flag", "required,required", nil, `duplicate annotation "required"`},
+		{"missing quote", "default='abc", nil, "missing closing quote"},
+		{"text after quote", "default='a'b,max=1", nil, `unexpected "b" after quoted value`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseAnnotations(tt.tag)
+			if len(tt.err) != 0 {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("got %q, %v, want the error %q", got, err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckAnnotations(t *testing.T) {
+	tests := []struct {
+		name  string
+		field string
+		// err is a part of the expected error, the field is valid if it is empty
+		err string
+	}{
+		{"string bounds", "F string `goproto:\"min=1,max=8,len=4\"`", ""},
+		{"slice bounds", "F []uint16 `goproto:\"min=0,max=0xffffffff\"`", ""},
+		{"byte slice len", "F []byte `goproto:\"len=16\"`", ""},
+		{"negative length", "F string `goproto:\"max=-1\"`", `max must be a length, got "-1"`},
+		{"length over uint32", "F []byte `goproto:\"max=0x100000000\"`", "max must be a length"},
+		{"length not a number", "F string `goproto:\"min=one\"`", `min must be a length, got "one"`},
+		{"uint8 range", "F uint8 `goproto:\"min=0,max=255\"`", ""},
+		{"uint8 over", "F uint8 `goproto:\"max=256\"`", `max must be an integer of type uint8, got "256"`},
+		{"byte negative", "F byte `goproto:\"min=-1\"`", "min must be an integer of type byte"},
+		{"int8 range", "F int8 `goproto:\"min=-128,max=127\"`", ""},
+		{"int8 under", "F int8 `goproto:\"min=-129\"`", "min must be an integer of type int8"},
+		{"int16 hex", "F int16 `goproto:\"max=0x7fff\"`", ""},
+		{"uint64 max", "F uint64 `goproto:\"max=18446744073709551615\"`", ""},
+		{"int64 over", "F int64 `goproto:\"max=9223372036854775808\"`", "max must be an integer of type int64"},
+		{"len on integer", "F uint32 `goproto:\"len=4\"`", "len is only allowed on string and slice fields"},
+		{"pattern", "F string `goproto:\"pattern='^[a-z]+$'\"`", ""},
+		{"pattern on slice", "F []byte `goproto:\"pattern=x\"`", "pattern is only allowed on string fields"},
+		{"invalid pattern", "F string `goproto:\"pattern=[a\"`", "invalid pattern"},
+		{"required", "F uint16 `goproto:\"required\"`", ""},
+		{"required with value", "F string `goproto:\"required=false\"`", `required takes no value, got "false"`},
+		{"string default", "F string `goproto:\"default=any text\"`", ""},
+		{"integer default", "F int32 `goproto:\"default=-0x10\"`", ""},
+		{"integer default over", "F uint16 `goproto:\"default=70000\"`", "default must be an integer of type uint16"},
+		{"default within bounds", "F int16 `goproto:\"min=-10,max=10,default=-10\"`", ""},
+		{"default below min", "F int16 `goproto:\"min=-10,default=-11\"`", "default -11 is less than min -10"},
+		{"default over max", "F uint32 `goproto:\"max=10,default=500\"`", "default 500 is greater than max 10"},
+		{"default over hex max", "F uint64 `goproto:\"max=0xff,default=0x100\"`", "default 0x100 is greater than max 0xff"},
+		{"zero default required", "F int8 `goproto:\"required,default=0\"`", "default is 0, but the field is required"},
+		{"string default fits", "F string `goproto:\"min=2,max=5,pattern='^[a-z]+$',required,default=abc\"`", ""},
+		{"string default too long", "F string `goproto:\"max=2,default=abc\"`", `default "abc" is longer than max 2`},
+		{"string default too short", "F string `goproto:\"min=4,default=abc\"`", `default "abc" is shorter than min 4`},
+		{"string default len", "F string `goproto:\"len=4,default=abc\"`", `default "abc" is 3 bytes long, len requires 4`},
+		{"string default pattern", "F string `goproto:\"pattern='^[0-9]+$',default=abc\"`", `default "abc" does not match pattern`},
+		{"empty default required", "F string `goproto:\"required,default=\"`", "default is empty, but the field is required"},
+		{"default on slice", "F []uint16 `goproto:\"default=1\"`", "default is not allowed on slice fields"},
+		{"on struct", "F S `goproto:\"required\"`", "required is not allowed on struct fields"},
+		{"on array", "F [2]uint16 `goproto:\"max=3\"`", "max is not allowed on array fields"},
+		{"unknown", "F string `goproto:\"maximum=3\"`", `unknown annotation "maximum"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseSchemaSource([]byte("package p\n\ntype S struct{ A int8 }\n\ntype T struct {\n\t" + tt.field + "\n}\n"))
+			if len(tt.err) == 0 {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("got %v, want the error %q", err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+func init() {
+	RegisterBackend("proto", protoBackend{})
+}
+
+// protoBackend exports the schema as a proto3 file, so that teams using protobuf can share it.
+// Every packet and struct becomes a message whose fields are numbered in declaration order,
+// the doc comments and the packet annotation are kept as comments. Types protobuf can't
+// express exactly, like uint16 or arrays, and the goproto tag of a field are noted by a
+// "goproto:" comment which ImportProto reads back.
+// The options are "package", the protobuf package, and "go_package".
+type protoBackend struct{}
+
+func (protoBackend) Generate(schema *Schema, opts Options) (map[string][]byte, error) {
+	t, err := loadTemplates("proto", opts, protoTemplateFuncs)
+	if err != nil {
+		return nil, err
+	}
+	code, err := executeTemplate(t, "proto", &templateData{Schema: schema, Options: opts})
+	if err != nil {
+		return nil, err
+	}
+	return map[string][]byte{snakeCase(schema.PackageName) + ".proto": code}, nil
+}
+
+// protoTemplateFuncs is shared by the export and the import, whose templates are parsed together.
+var protoTemplateFuncs = template.FuncMap{
+	"protoField": protoField,
+	"protoDoc":   protoDoc,
+	"snakeCase":  snakeCase,
+}
+
+// protoDoc returns the lines of a doc comment as // comments, each one indented and
+// followed by a newline.
+func protoDoc(doc, indent string) string {
+	if len(doc) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	for _, line := range strings.Split(doc, "\n") {
+		if b.WriteString(indent + "//"); len(line) != 0 {
+			b.WriteString(" " + line)
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+var protoScalarTypes = map[FieldKind]string{
+	ByteFieldKind:   "uint32",
+	Uint8FieldKind:  "uint32",
+	Uint16FieldKind: "uint32",
+	Uint32FieldKind: "uint32",
+	Uint64FieldKind: "uint64",
+	Int8FieldKind:   "int32",
+	Int16FieldKind:  "int32",
+	Int32FieldKind:  "int32",
+	Int64FieldKind:  "int64",
+	StringFieldKind: "string",
+}
+
+// protoField returns the declaration of the field numbered number, without indentation.
+// The goproto comment after it gives the Go name, the Go type and the goproto tag, if any.
+func protoField(f *FieldLayout, number int) (string, error) {
+	typ := protoScalarTypes[f.subElementKind]
+	if f.subElementKind == StructFieldKind {
+		typ = f.fieldType
+	}
+	isByte := f.subElementKind == ByteFieldKind || f.subElementKind == Uint8FieldKind
+	switch {
+	case (f.kind == SliceFieldKind || f.kind == ArrayFieldKind) && isByte:
+		typ = "bytes"
+	case f.kind == SliceFieldKind || f.kind == ArrayFieldKind:
+		typ = "repeated " + typ
+	}
+	name := snakeCase(f.name)
+	decl := fmt.Sprintf("%s %s = %d;", typ, name, number)
+	tag := formatAnnotations(f.annotations)
+	if strings.ContainsAny(tag, "\r\n") {
+		return "", fmt.Errorf("field %s: the goproto tag can't be exported across lines", f.Path())
+	}
+	if camelCase(name) != f.name || protoGoType(typ) != goType(f) || len(tag) != 0 {
+		decl += fmt.Sprintf(" // goproto: %s %s", f.name, goType(f))
+		if len(tag) != 0 {
+			decl += " " + tag
+		}
+	}
+	return decl, nil
+}
+
+// camelCase converts a snake case protobuf name into a Go field name, user_name becomes UserName.
+func camelCase(name string) string {
+	parts := strings.Split(name, "_")
+	for i, part := range parts {
+		if len(part) != 0 {
+			parts[i] = strings.ToUpper(part[:1]) + part[1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+var protoGoTypes = map[string]string{
+	"int32":    "int32",
+	"sint32":   "int32",
+	"sfixed32": "int32",
+	"uint32":   "uint32",
+	"fixed32":  "uint32",
+	"int64":    "int64",
+	"sint64":   "int64",
+	"sfixed64": "int64",
+	"uint64":   "uint64",
+	"fixed64":  "uint64",
+	"bool":     "uint8",
+	"string":   "string",
+	"bytes":    "[]byte",
+}
+
+// protoGoType returns the Go type a protobuf field type is imported as, typ may
+// be prefixed by "repeated". Message and enum names are returned unchanged.
+func protoGoType(typ string) string {
+	prefix := ""
+	if strings.HasPrefix(typ, "repeated ") {
+		prefix, typ = "[]", strings.TrimPrefix(typ, "repeated ")
+	}
+	if goType, ok := protoGoTypes[typ]; ok {
+		return prefix + goType
+	}
+	return prefix + typ
+}
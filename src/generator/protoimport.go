@@ -0,0 +1,504 @@
+package generator
+
+import (
+	"fmt"
+	"go/format"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ImportProto converts a .proto file into a goproto definition file. Messages become structs,
+// their leading comments, including the packet annotations written by the proto backend, and
+// the comments of their fields are kept. Nested messages are named after their parents, e.g.
+// Outer.Inner becomes Outer_Inner. A trailing "goproto: <Name> <Type> [<tag>]" comment on a
+// field overrides the name and the type derived from the protobuf declaration, the rest is the
+// content of the goproto tag of the field. The message PacketHeader is skipped, the option
+// "package" sets the Go package name, which defaults to the last part of the protobuf package.
+func ImportProto(data []byte, opts Options) ([]byte, error) {
+	tokens, err := tokenizeProto(string(data))
+	if err != nil {
+		return nil, err
+	}
+	p := &protoImporter{tokens: tokens, enums: make(map[string]bool)}
+	if err = p.parseFile(); err != nil {
+		return nil, err
+	}
+	def := &protoDefinition{Package: opts.Get("package", p.pkg)}
+	if len(def.Package) == 0 {
+		def.Package = "protocol"
+	}
+	messages := make(map[string]string)
+	names := make(map[string]string)
+	for _, m := range p.messages {
+		if other, ok := names[m.Name]; ok {
+			return nil, fmt.Errorf("messages %s and %s are both named %s in Go", other, m.path, m.Name)
+		}
+		names[m.Name] = m.path
+		messages[m.path] = m.Name
+	}
+	for _, m := range p.messages {
+		if m.path == "PacketHeader" {
+			continue
+		}
+		if err = p.resolve(m, messages); err != nil {
+			return nil, err
+		}
+		def.Messages = append(def.Messages, m)
+	}
+	t, err := loadTemplates("proto", opts, protoTemplateFuncs)
+	if err != nil {
+		return nil, err
+	}
+	code, err := executeTemplate(t, "definition", def)
+	if err != nil {
+		return nil, err
+	}
+	if code, err = format.Source(code); err != nil {
+		return nil, fmt.Errorf("generated definition is invalid: %s", err)
+	}
+	return code, nil
+}
+
+type protoDefinition struct {
+	Package  string
+	Messages []*protoMessage
+}
+
+type protoMessage struct {
+	Name     string
+	Comments []string
+	Fields   []*protoFieldDecl
+	// path is the protobuf name relative to the package, e.g. Outer.Inner.
+	path string
+}
+
+type protoFieldDecl struct {
+	Name   string
+	Type   string
+	Number int
+	// Tag is the Go struct tag carrying the annotations of a "goproto:" comment.
+	Tag string
+	// Comments are the comment lines above the field, Comment the one after it.
+	Comments []string
+	Comment  string
+	// Hint is the content of a trailing "goproto:" comment.
+	Hint     string
+	label    string
+	protoTyp string
+	line     int
+}
+
+const (
+	protoIdent = iota
+	protoNumber
+	protoString
+	protoSymbol
+	protoComment
+)
+
+type protoToken struct {
+	kind int
+	text string
+	line int
+}
+
+// tokenizeProto splits a .proto file into tokens, line comments are kept as tokens
+// since they carry the annotations, block comments are dropped.
+func tokenizeProto(src string) ([]protoToken, error) {
+	var tokens []protoToken
+	line := 1
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case strings.HasPrefix(src[i:], "//"):
+			end := strings.IndexByte(src[i:], '\n')
+			if end < 0 {
+				end = len(src) - i
+			}
+			tokens = append(tokens, protoToken{protoComment, strings.TrimSpace(src[i+2 : i+end]), line})
+			i += end
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated comment", line)
+			}
+			line += strings.Count(src[i:i+2+end], "\n")
+			i += end + 4
+		case c == '"' || c == '\'':
+			end := i + 1
+			for end < len(src) && src[end] != c {
+				if src[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(src) {
+				return nil, fmt.Errorf("line %d: unterminated string", line)
+			}
+			tokens = append(tokens, protoToken{protoString, src[i+1 : end], line})
+			i = end + 1
+		case c == '_' || c == '.' || unicode.IsLetter(rune(c)):
+			end := i
+			for end < len(src) && (src[end] == '_' || src[end] == '.' || unicode.IsLetter(rune(src[end])) || unicode.IsDigit(rune(src[end]))) {
+				end++
+			}
+			tokens = append(tokens, protoToken{protoIdent, src[i:end], line})
+			i = end
+		case c == '-' || unicode.IsDigit(rune(c)):
+			end := i + 1
+			for end < len(src) && (unicode.IsLetter(rune(src[end])) || unicode.IsDigit(rune(src[end])) || src[end] == '.') {
+				end++
+			}
+			tokens = append(tokens, protoToken{protoNumber, src[i:end], line})
+			i = end
+		default:
+			tokens = append(tokens, protoToken{protoSymbol, string(c), line})
+			i++
+		}
+	}
+	return tokens, nil
+}
+
+type protoImporter struct {
+	tokens   []protoToken
+	pos      int
+	pkg      string
+	fullPkg  string
+	messages []*protoMessage
+	// enums holds the paths of the enums, like the path of a message.
+	enums    map[string]bool
+	comments []string
+}
+
+func (p *protoImporter) peek() *protoToken {
+	if p.pos < len(p.tokens) {
+		return &p.tokens[p.pos]
+	}
+	return nil
+}
+
+func (p *protoImporter) next() (protoToken, error) {
+	if p.pos >= len(p.tokens) {
+		return protoToken{}, fmt.Errorf("unexpected end of file")
+	}
+	p.pos++
+	return p.tokens[p.pos-1], nil
+}
+
+func (p *protoImporter) expect(text string) error {
+	tok, err := p.next()
+	if err != nil {
+		return err
+	}
+	if tok.text != text || tok.kind == protoString || tok.kind == protoComment {
+		return fmt.Errorf("line %d: expected %q, found %q", tok.line, text, tok.text)
+	}
+	return nil
+}
+
+func (p *protoImporter) ident() (protoToken, error) {
+	tok, err := p.next()
+	if err == nil && tok.kind != protoIdent {
+		err = fmt.Errorf("line %d: expected identifier, found %q", tok.line, tok.text)
+	}
+	return tok, err
+}
+
+// skipStatement skips the tokens up to and including the next ";".
+func (p *protoImporter) skipStatement() error {
+	for {
+		tok, err := p.next()
+		if err != nil {
+			return err
+		}
+		if tok.kind == protoSymbol && tok.text == ";" {
+			return nil
+		}
+	}
+}
+
+// skipBlock skips a "{ ... }" block, the opening brace is the next non comment token.
+func (p *protoImporter) skipBlock() error {
+	depth := 0
+	for {
+		tok, err := p.next()
+		if err != nil {
+			return err
+		}
+		if tok.kind != protoSymbol {
+			continue
+		}
+		switch tok.text {
+		case "{":
+			depth++
+		case "}":
+			if depth--; depth == 0 {
+				return nil
+			}
+		}
+	}
+}
+
+func (p *protoImporter) parseFile() error {
+	for p.peek() != nil {
+		tok, _ := p.next()
+		if tok.kind == protoComment {
+			p.comments = append(p.comments, tok.text)
+			continue
+		}
+		if tok.kind == protoSymbol && tok.text == ";" {
+			continue
+		}
+		if tok.kind != protoIdent {
+			return fmt.Errorf("line %d: unexpected %q", tok.line, tok.text)
+		}
+		comments := p.comments
+		p.comments = nil
+		var err error
+		switch tok.text {
+		case "package":
+			var name protoToken
+			if name, err = p.ident(); err == nil {
+				p.pkg, p.fullPkg = name.text[strings.LastIndex(name.text, ".")+1:], name.text
+				err = p.expect(";")
+			}
+		case "syntax", "edition", "import", "option":
+			err = p.skipStatement()
+		case "message":
+			err = p.parseMessage(comments, "")
+		case "enum":
+			err = p.parseEnum("")
+		case "service", "extend":
+			if _, err = p.ident(); err == nil {
+				err = p.skipBlock()
+			}
+		default:
+			err = fmt.Errorf("line %d: unexpected %q", tok.line, tok.text)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// protoPath joins the path of the enclosing message and a name.
+func protoPath(scope, name string) string {
+	if len(scope) == 0 {
+		return name
+	}
+	return scope + "." + name
+}
+
+func (p *protoImporter) parseEnum(scope string) error {
+	name, err := p.ident()
+	if err != nil {
+		return err
+	}
+	p.enums[protoPath(scope, name.text)] = true
+	return p.skipBlock()
+}
+
+// parseMessage parses a message declared in the message scope, or at the top level if scope is empty.
+func (p *protoImporter) parseMessage(comments []string, scope string) error {
+	name, err := p.ident()
+	if err != nil {
+		return err
+	}
+	if err = p.expect("{"); err != nil {
+		return err
+	}
+	path := protoPath(scope, name.text)
+	m := &protoMessage{Name: strings.Replace(path, ".", "_", -1), Comments: comments, path: path}
+	var last *protoFieldDecl
+	// pending holds the comments on their own lines since the last declaration.
+	var pending []string
+	for {
+		tok, err := p.next()
+		if err != nil {
+			return err
+		}
+		switch {
+		case tok.kind == protoComment:
+			if last != nil && last.line == tok.line {
+				if strings.HasPrefix(tok.text, "goproto:") {
+					last.Hint = strings.TrimSpace(strings.TrimPrefix(tok.text, "goproto:"))
+				} else {
+					last.Comment = tok.text
+				}
+			} else {
+				pending = append(pending, tok.text)
+			}
+			continue
+		case tok.kind == protoSymbol && tok.text == "}":
+			sort.SliceStable(m.Fields, func(i, j int) bool { return m.Fields[i].Number < m.Fields[j].Number })
+			p.messages = append(p.messages, m)
+			return nil
+		case tok.kind == protoSymbol && tok.text == ";":
+			continue
+		case tok.kind != protoIdent:
+			return fmt.Errorf("line %d: unexpected %q in message %s", tok.line, tok.text, m.path)
+		}
+		comments := pending
+		pending = nil
+		switch tok.text {
+		case "message":
+			// nested messages are declared at the top level of the definition file
+			if err = p.parseMessage(comments, path); err != nil {
+				return err
+			}
+		case "enum":
+			if err = p.parseEnum(path); err != nil {
+				return err
+			}
+		case "option", "reserved", "extensions":
+			if err = p.skipStatement(); err != nil {
+				return err
+			}
+		case "oneof", "map", "group":
+			return fmt.Errorf("line %d: %s in message %s is not supported", tok.line, tok.text, m.path)
+		default:
+			p.pos--
+			if last, err = p.parseField(); err != nil {
+				return err
+			}
+			last.Comments = comments
+			m.Fields = append(m.Fields, last)
+		}
+	}
+}
+
+func (p *protoImporter) parseField() (*protoFieldDecl, error) {
+	f := &protoFieldDecl{}
+	typ, err := p.ident()
+	if err != nil {
+		return nil, err
+	}
+	switch typ.text {
+	case "repeated", "optional", "required":
+		f.label = typ.text
+		if typ, err = p.ident(); err != nil {
+			return nil, err
+		}
+	}
+	if strings.HasPrefix(typ.text, "map") {
+		return nil, fmt.Errorf("line %d: map fields are not supported", typ.line)
+	}
+	f.protoTyp = typ.text
+	name, err := p.ident()
+	if err != nil {
+		return nil, err
+	}
+	f.Name = name.text
+	if err = p.expect("="); err != nil {
+		return nil, err
+	}
+	number, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	if f.Number, err = strconv.Atoi(number.text); err != nil {
+		return nil, fmt.Errorf("line %d: invalid field number %q", number.line, number.text)
+	}
+	for {
+		tok, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		if tok.kind == protoSymbol && tok.text == ";" {
+			f.line = tok.line
+			return f, nil
+		}
+	}
+}
+
+// resolve sets the Go names and types of the message fields, messages maps the
+// paths of the messages to their Go names.
+func (p *protoImporter) resolve(m *protoMessage, messages map[string]string) error {
+	for _, f := range m.Fields {
+		if len(f.Hint) != 0 {
+			parts := strings.SplitN(f.Hint, " ", 3)
+			if len(parts) < 2 || len(parts[0]) == 0 || len(parts[1]) == 0 {
+				return fmt.Errorf("message %s: invalid goproto comment %q", m.Name, f.Hint)
+			}
+			f.Name, f.Type = parts[0], parts[1]
+			if len(parts) == 3 {
+				if _, err := parseAnnotations(parts[2]); err != nil {
+					return fmt.Errorf("message %s: field %s: %s", m.Name, f.Name, err)
+				}
+				f.Tag = goStructTag(parts[2])
+			}
+			continue
+		}
+		typ := f.protoTyp
+		switch {
+		case typ == "double" || typ == "float":
+			return fmt.Errorf("message %s: floating point field %s is not supported", m.Name, f.Name)
+		case typ == "bytes" && f.label == "repeated":
+			return fmt.Errorf("message %s: repeated bytes field %s is not supported", m.Name, f.Name)
+		case protoGoTypes[typ] != "":
+		default:
+			name, isEnum := p.lookupType(m.path, typ, messages)
+			switch {
+			case isEnum:
+				typ = "int32"
+			case len(name) != 0:
+				typ = name
+			default:
+				return fmt.Errorf("message %s: unknown type %s of field %s", m.Name, typ, f.Name)
+			}
+		}
+		if f.label == "repeated" {
+			typ = "repeated " + typ
+		}
+		f.Name, f.Type = camelCase(f.Name), protoGoType(typ)
+	}
+	return nil
+}
+
+// goStructTag returns the Go struct tag with the goproto annotations tag.
+func goStructTag(tag string) string {
+	if tag = "goproto:" + strconv.Quote(tag); strings.Contains(tag, "`") {
+		return strconv.Quote(tag)
+	}
+	return "`" + tag + "`"
+}
+
+// lookupType resolves a message or enum type referenced from the message scope like protobuf
+// does, searching the scope and its parents from the innermost one. A name starting with "."
+// is fully qualified. It returns the Go name of a message, or isEnum for an enum.
+func (p *protoImporter) lookupType(scope, typ string, messages map[string]string) (name string, isEnum bool) {
+	names := []string{typ}
+	if strings.HasPrefix(typ, ".") {
+		names, scope = []string{typ[1:]}, ""
+	}
+	if len(p.fullPkg) != 0 && strings.HasPrefix(names[0], p.fullPkg+".") {
+		names = append(names, strings.TrimPrefix(names[0], p.fullPkg+"."))
+	}
+	for {
+		for _, n := range names {
+			path := protoPath(scope, n)
+			if name, ok := messages[path]; ok {
+				return name, false
+			}
+			if p.enums[path] {
+				return "", true
+			}
+		}
+		if len(scope) == 0 {
+			return "", false
+		}
+		if i := strings.LastIndex(scope, "."); i >= 0 {
+			scope = scope[:i]
+		} else {
+			scope = ""
+		}
+	}
+}
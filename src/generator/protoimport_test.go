@@ -0,0 +1,81 @@
+package generator
+
+import (
+	"reflect"
+	"testing"
+)
+
+const protoTestSchema = `package protocol
+
+// Point is a position
+// on the map.
+type Point struct {
+	X int32 ` + "`goproto:\"min=-5,max=5,default=-1\"`" + `
+	Y int16 // the row
+}
+
+// LoginRequest logs a user in.
+//
+// It is sent first.
+// @Packet: LOGIN_REQUEST, 0x00000002
+type LoginRequest struct {
+	// UserName is the account.
+	UserName string ` + "`goproto:\"required,max=8,pattern='^[a-z]{1,8}$',default=guest\"`" + `
+	Nick     string ` + "`goproto:\"default=' it\\\\'s, odd\\\\\\\\'\"`" + `
+	Note     string "goproto:\"default='` + "`" + ` '\""
+	Age      uint32 ` + "`goproto:\"min=1,max=200,default=18\"`" + `
+	Pos      [2]Point
+	Raw      []byte ` + "`goproto:\"len=4\"`" + `
+	Track    []Point
+}
+
+// @SimplePacket: KEEPALIVE, 0x00000001
+type Keepalive struct{}
+
+// @VLFPacket: LIST, 0x80000002
+type List struct {
+	Items []Point ` + "`goproto:\"max=3\"`" + `
+}
+`
+
+func TestProtoRoundTrip(t *testing.T) {
+	schema, err := ParseSchemaSource([]byte(protoTestSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := protoBackend{}.Generate(schema, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, err := ImportProto(files["protocol.proto"], nil)
+	if err != nil {
+		t.Fatalf("ImportProto: %v\n%s", err, files["protocol.proto"])
+	}
+	imported, err := ParseSchemaSource(code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(imported.Packets) != len(schema.Packets) {
+		t.Fatalf("%d types imported, want %d\n%s", len(imported.Packets), len(schema.Packets), code)
+	}
+	for i, want := range schema.Packets {
+		got := imported.Packets[i]
+		if got.Name() != want.Name() || got.Kind() != want.Kind() || got.ID() != want.ID() || got.IDName() != want.IDName() || got.Doc() != want.Doc() {
+			t.Errorf("type %s imported as %s %s %s 0x%x %q", want.Name(), got.Kind(), got.Name(), got.IDName(), got.ID(), got.Doc())
+			continue
+		}
+		if len(got.Fields()) != len(want.Fields()) {
+			t.Errorf("type %s: %d fields imported, want %d", want.Name(), len(got.Fields()), len(want.Fields()))
+			continue
+		}
+		for j, w := range want.Fields() {
+			g := got.Fields()[j]
+			if g.Name() != w.Name() || goType(g) != goType(w) || g.Doc() != w.Doc() {
+				t.Errorf("field %s %s %q imported as %s %s %q", w.Path(), goType(w), w.Doc(), g.Path(), goType(g), g.Doc())
+			}
+			if !reflect.DeepEqual(g.annotations, w.annotations) {
+				t.Errorf("field %s: annotations %q imported as %q", w.Path(), w.annotations, g.annotations)
+			}
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+func init() {
+	RegisterBackend("python", pyBackend{})
+}
+
+// pyBackend generates a Python module with a dataclass per packet and struct,
+// encode()/decode() functions and a factory keyed by the packet type.
+type pyBackend struct{}
+
+func (pyBackend) Generate(schema *Schema, opts Options) (map[string][]byte, error) {
+	endian, err := opts.Endian()
+	if err != nil {
+		return nil, err
+	}
+	t, err := loadTemplates("python", opts, template.FuncMap{
+		"pyFormat":  pyFormat,
+		"pyRead":    pyRead,
+		"pyWrite":   pyWrite,
+		"pyDefault": pyDefault,
+		"pyType":    pyType,
+		"isByte":    func(k FieldKind) bool { return k == ByteFieldKind || k == Uint8FieldKind },
+	})
+	if err != nil {
+		return nil, err
+	}
+	code, err := executeTemplate(t, "module", &templateData{Schema: schema, Options: opts, Endian: endian})
+	if err != nil {
+		return nil, err
+	}
+	return map[string][]byte{strings.ToLower(schema.PackageName) + ".py": code}, nil
+}
+
+var pyFormats = map[FieldKind]string{
+	ByteFieldKind:   "B",
+	Uint8FieldKind:  "B",
+	Uint16FieldKind: "H",
+	Uint32FieldKind: "I",
+	Uint64FieldKind: "Q",
+	Int8FieldKind:   "b",
+	Int16FieldKind:  "h",
+	Int32FieldKind:  "i",
+	Int64FieldKind:  "q",
+}
+
+// pyFormat returns the struct module format character of a fixed size kind.
+func pyFormat(k FieldKind) string { return pyFormats[k] }
+
+// pyRead returns the expression reading a single value of kind k from the reader r.
+func pyRead(k FieldKind, typeName string) string {
+	switch k {
+	case StringFieldKind:
+		return "r.string()"
+	case StructFieldKind:
+		return typeName + ".read(r)"
+	}
+	return fmt.Sprintf("r.unpack(%q)", pyFormat(k))
+}
+
+// pyWrite returns the statement writing the value v of kind k to the writer w.
+func pyWrite(k FieldKind, v string) string {
+	switch k {
+	case StringFieldKind:
+		return "w.string(" + v + ")"
+	case StructFieldKind:
+		return v + ".write(w)"
+	}
+	return fmt.Sprintf("w.pack(%q, %s)", pyFormat(k), v)
+}
+
+func pyElemType(k FieldKind, typeName string) string {
+	switch k {
+	case StringFieldKind:
+		return "str"
+	case StructFieldKind:
+		return typeName
+	}
+	return "int"
+}
+
+// pyType returns the annotation of a field.
+func pyType(f *FieldLayout) string {
+	switch f.kind {
+	case SliceFieldKind, ArrayFieldKind:
+		if f.subElementKind == ByteFieldKind || f.subElementKind == Uint8FieldKind {
+			return "bytes"
+		}
+		return "List[" + pyElemType(f.subElementKind, f.fieldType) + "]"
+	}
+	return pyElemType(f.kind, f.fieldType)
+}
+
+func pyElemDefault(k FieldKind, typeName string) string {
+	switch k {
+	case StringFieldKind:
+		return `""`
+	case StructFieldKind:
+		return typeName + "()"
+	}
+	return "0"
+}
+
+// pyDefault returns the dataclass default of a field.
+func pyDefault(f *FieldLayout) string {
+	isByte := f.subElementKind == ByteFieldKind || f.subElementKind == Uint8FieldKind
+	switch f.kind {
+	case SliceFieldKind:
+		if isByte {
+			return `b""`
+		}
+		return "field(default_factory=list)"
+	case ArrayFieldKind:
+		if isByte {
+			return fmt.Sprintf("bytes(%d)", f.arrayLen)
+		}
+		return fmt.Sprintf("field(default_factory=lambda: [%s for _ in range(%d)])", pyElemDefault(f.subElementKind, f.fieldType), f.arrayLen)
+	case StructFieldKind:
+		return "field(default_factory=" + f.fieldType + ")"
+	}
+	return pyElemDefault(f.kind, f.fieldType)
+}
@@ -0,0 +1,198 @@
+package generator
+
+import (
+	"fmt"
+	"math/rand"
+	"regexp"
+	"regexp/syntax"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// goRandomFuncs returns the template functions choosing the random values of the generated
+// tests. With validate the values meet the annotations of their fields, which Validate checks,
+// otherwise they only keep within the max length Read enforces.
+func goRandomFuncs(validate bool) template.FuncMap {
+	return template.FuncMap{
+		"randomLength": func(f *FieldLayout) string { return goRandomLength(f, validate) },
+		"randomRange": func(f *FieldLayout) *goRandomRange {
+			if !validate {
+				return nil
+			}
+			return goRandomRangeOf(f)
+		},
+		"randomPattern": func(f *FieldLayout) bool {
+			_, ok := f.annotations["pattern"]
+			return validate && ok
+		},
+		"randomSamples": goPatternSamples,
+	}
+}
+
+// goLengthBounds returns the bounds of the length of a string or slice field, hi is -1 if
+// there is no upper bound. With validate they are the bounds of the annotations, otherwise
+// only max, which limits reading, applies.
+func goLengthBounds(f *FieldLayout, validate bool) (lo, hi int) {
+	if !validate {
+		return 0, f.LengthMax()
+	}
+	hi = -1
+	if n, ok := f.annotations["len"]; ok {
+		v, _ := strconv.ParseInt(n, 0, 64)
+		return int(v), int(v)
+	}
+	if n, ok := f.annotations["min"]; ok {
+		v, _ := strconv.ParseInt(n, 0, 64)
+		lo = int(v)
+	}
+	if n, ok := f.annotations["max"]; ok {
+		v, _ := strconv.ParseInt(n, 0, 64)
+		hi = int(v)
+	}
+	if _, ok := f.annotations["required"]; ok && lo == 0 {
+		lo = 1
+	}
+	return lo, hi
+}
+
+// goRandomLength returns the arguments "lo, hi" of randomLen for a string or slice field of
+// the generated tests. Without an upper bound, or a large one, the length exceeds the lower
+// bound by at most 15 bytes or 3 elements.
+func goRandomLength(f *FieldLayout, validate bool) string {
+	lo, hi := goLengthBounds(f, validate)
+	extra := 15
+	if f.kind == SliceFieldKind && f.subElementKind != ByteFieldKind && f.subElementKind != Uint8FieldKind {
+		extra = 3
+	}
+	if hi < 0 || hi > lo+extra {
+		hi = lo + extra
+	}
+	return fmt.Sprintf("%d, %d", lo, hi)
+}
+
+// goRandomRange is the range randomize draws an annotated integer field from with Func,
+// NonZero is the value replacing 0 if the field is required and the range includes 0.
+type goRandomRange struct {
+	Func, Lo, Hi, NonZero string
+}
+
+// goRandomRangeOf returns the range of an integer field with min, max or required
+// annotations, or nil if any value of its type is valid.
+func goRandomRangeOf(f *FieldLayout) *goRandomRange {
+	min, hasMin := f.annotations["min"]
+	max, hasMax := f.annotations["max"]
+	_, required := f.annotations["required"]
+	if !hasMin && !hasMax && !required {
+		return nil
+	}
+	size := uint(f.kind.Size() * 8)
+	if f.kind.IsSigned() {
+		lo, hi := int64(-1)<<(size-1), int64(1)<<(size-1)-1
+		if hasMin {
+			lo, _ = strconv.ParseInt(min, 0, 64)
+		}
+		if hasMax {
+			hi, _ = strconv.ParseInt(max, 0, 64)
+		}
+		r := &goRandomRange{Func: "randomInt", Lo: strconv.FormatInt(lo, 10), Hi: strconv.FormatInt(hi, 10)}
+		if required && lo <= 0 && hi >= 0 {
+			r.NonZero = r.Hi
+			if hi == 0 {
+				r.NonZero = r.Lo
+			}
+		}
+		return r
+	}
+	lo, hi := uint64(0), uint64(1)<<(size-1)<<1-1
+	if hasMin {
+		lo, _ = strconv.ParseUint(min, 0, 64)
+	}
+	if hasMax {
+		hi, _ = strconv.ParseUint(max, 0, 64)
+	}
+	if required && lo == 0 {
+		lo = 1
+	}
+	return &goRandomRange{Func: "randomUint", Lo: strconv.FormatUint(lo, 10), Hi: strconv.FormatUint(hi, 10)}
+}
+
+// goPatternSamples returns up to 4 strings matching the pattern of a string field within its
+// length bounds, randomize picks one of them. They are generated from the parsed pattern with
+// a fixed seed, so that the generated code doesn't change between runs. If none is found the
+// result is empty and the field keeps its initial value, the default if it has one.
+func goPatternSamples(f *FieldLayout) []string {
+	pattern := f.annotations["pattern"]
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		// checkAnnotations rejects invalid patterns
+		return nil
+	}
+	matcher := regexp.MustCompile(pattern)
+	lo, hi := goLengthBounds(f, true)
+	r := rand.New(rand.NewSource(1))
+	var samples []string
+	seen := make(map[string]bool)
+	for i := 0; i < 1000 && len(samples) < 4; i++ {
+		var b strings.Builder
+		goSampleRegexp(r, re.Simplify(), &b)
+		s := b.String()
+		if !seen[s] && len(s) >= lo && (hi < 0 || len(s) <= hi) && matcher.MatchString(s) {
+			seen[s] = true
+			samples = append(samples, s)
+		}
+	}
+	return samples
+}
+
+// goSampleRegexp writes a random text matched by re to b. Repetitions without an upper
+// bound repeat at most 3 times more than required, characters are printable ASCII where
+// the class allows it.
+func goSampleRegexp(r *rand.Rand, re *syntax.Regexp, b *strings.Builder) {
+	repeat := func(min, max int) {
+		if max < 0 {
+			max = min + 3
+		}
+		for n := min + r.Intn(max-min+1); n > 0; n-- {
+			goSampleRegexp(r, re.Sub[0], b)
+		}
+	}
+	switch re.Op {
+	case syntax.OpLiteral:
+		b.WriteString(string(re.Rune))
+	case syntax.OpCharClass:
+		var ascii, all []rune
+		for i := 0; i+1 < len(re.Rune); i += 2 {
+			for c := re.Rune[i]; c <= re.Rune[i+1] && len(all) < 256; c++ {
+				if c >= ' ' && c <= '~' {
+					ascii = append(ascii, c)
+				}
+				all = append(all, c)
+			}
+		}
+		if len(ascii) == 0 {
+			ascii = all
+		}
+		if len(ascii) != 0 {
+			b.WriteRune(ascii[r.Intn(len(ascii))])
+		}
+	case syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		b.WriteByte(byte('a' + r.Intn(26)))
+	case syntax.OpCapture:
+		goSampleRegexp(r, re.Sub[0], b)
+	case syntax.OpStar:
+		repeat(0, -1)
+	case syntax.OpPlus:
+		repeat(1, -1)
+	case syntax.OpQuest:
+		repeat(0, 1)
+	case syntax.OpRepeat:
+		repeat(re.Min, re.Max)
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			goSampleRegexp(r, sub, b)
+		}
+	case syntax.OpAlternate:
+		goSampleRegexp(r, re.Sub[r.Intn(len(re.Sub))], b)
+	}
+}
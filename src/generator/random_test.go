@@ -0,0 +1,128 @@
+package generator
+
+import (
+	"math/rand"
+	"reflect"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+	"testing"
+)
+
+// testField returns the field F declared as decl, e.g. "string `goproto:\"max=3\"`".
+func testField(t *testing.T, decl string) *FieldLayout {
+	t.Helper()
+	schema, err := ParseSchemaSource([]byte("package p\n\ntype T struct {\n\tF " + decl + "\n}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return schema.Packets[0].Fields()[0]
+}
+
+func TestSampleRegexp(t *testing.T) {
+	for _, pattern := range []string{
+		`^[a-z][a-z0-9_]*$`,
+		`^(foo|bar)-\d{2,4}$`,
+		`^a?b+c*$`,
+		`^\w+@example\.(com|org)$`,
+		`^[^a-z]{3}$`,
+		`^.{5}$`,
+		`^(?i)hello$`,
+		`^[\x{4e00}-\x{9fff}]+$`,
+		`x(y|)z`,
+	} {
+		re, err := syntax.Parse(pattern, syntax.Perl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		matcher := regexp.MustCompile(pattern)
+		r := rand.New(rand.NewSource(1))
+		for i := 0; i < 100; i++ {
+			var b strings.Builder
+			goSampleRegexp(r, re.Simplify(), &b)
+			if !matcher.MatchString(b.String()) {
+				t.Errorf("sample %q does not match %s", b.String(), pattern)
+			}
+		}
+	}
+}
+
+func TestPatternSamples(t *testing.T) {
+	tests := []struct {
+		decl string
+		// n is the number of samples expected
+		n int
+	}{
+		{"string `goproto:\"pattern='^[a-z]+$'\"`", 4},
+		{"string `goproto:\"pattern='^[a-z]+$',max=2\"`", 4},
+		{"string `goproto:\"pattern='^(yes|no)$'\"`", 2},
+		{"string `goproto:\"pattern='^(yes|no)$',len=3\"`", 1},
+		{"string `goproto:\"pattern='^[0-9]+$',len=12\"`", 0},
+		{"string `goproto:\"pattern='^[0-9]+$',min=8,default=12345678\"`", 0},
+	}
+	for _, tt := range tests {
+		f := testField(t, tt.decl)
+		samples := goPatternSamples(f)
+		if len(samples) != tt.n {
+			t.Errorf("%s: got %q, want %d samples", tt.decl, samples, tt.n)
+		}
+		lo, hi := goLengthBounds(f, true)
+		for _, s := range samples {
+			if !regexp.MustCompile(f.annotations["pattern"]).MatchString(s) || len(s) < lo || (hi >= 0 && len(s) > hi) {
+				t.Errorf("%s: sample %q is invalid", tt.decl, s)
+			}
+		}
+		if again := goPatternSamples(f); !reflect.DeepEqual(again, samples) {
+			t.Errorf("%s: got %q, then %q", tt.decl, samples, again)
+		}
+	}
+}
+
+func TestRandomLength(t *testing.T) {
+	tests := []struct {
+		decl                string
+		validate, unchecked string
+	}{
+		{"string", "0, 15", "0, 15"},
+		{"string `goproto:\"max=4\"`", "0, 4", "0, 4"},
+		{"string `goproto:\"min=2,max=100\"`", "2, 17", "0, 15"},
+		{"string `goproto:\"len=20\"`", "20, 20", "0, 15"},
+		{"string `goproto:\"required\"`", "1, 16", "0, 15"},
+		{"[]byte `goproto:\"min=40\"`", "40, 55", "0, 15"},
+		{"[]uint32 `goproto:\"max=2,required\"`", "1, 2", "0, 2"},
+		{"[]string", "0, 3", "0, 3"},
+	}
+	for _, tt := range tests {
+		f := testField(t, tt.decl)
+		if got := goRandomLength(f, true); got != tt.validate {
+			t.Errorf("%s with validate: got %s, want %s", tt.decl, got, tt.validate)
+		}
+		if got := goRandomLength(f, false); got != tt.unchecked {
+			t.Errorf("%s: got %s, want %s", tt.decl, got, tt.unchecked)
+		}
+	}
+}
+
+func TestRandomRange(t *testing.T) {
+	tests := []struct {
+		decl string
+		want *goRandomRange
+	}{
+		{"uint16", nil},
+		{"uint8 `goproto:\"max=9\"`", &goRandomRange{Func: "randomUint", Lo: "0", Hi: "9"}},
+		{"uint64 `goproto:\"required\"`", &goRandomRange{Func: "randomUint", Lo: "1", Hi: "18446744073709551615"}},
+		{"int8 `goproto:\"min=-3\"`", &goRandomRange{Func: "randomInt", Lo: "-3", Hi: "127"}},
+		{"int32 `goproto:\"min=-5,max=5,required\"`", &goRandomRange{Func: "randomInt", Lo: "-5", Hi: "5", NonZero: "5"}},
+		{"int16 `goproto:\"min=-5,max=0,required\"`", &goRandomRange{Func: "randomInt", Lo: "-5", Hi: "0", NonZero: "-5"}},
+	}
+	for _, tt := range tests {
+		f := testField(t, tt.decl)
+		if got := goRandomRangeOf(f); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %+v, want %+v", tt.decl, got, tt.want)
+		}
+		// the annotations only constrain the values with validate
+		if got := goRandomFuncs(false)["randomRange"].(func(*FieldLayout) *goRandomRange)(f); got != nil {
+			t.Errorf("%s without validate: got %+v, want no range", tt.decl, got)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package generator
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+//go:embed templates
+var templateFS embed.FS
+
+// templateData is handed to the templates, it is the schema plus the backend options.
+type templateData struct {
+	*Schema
+	Options Options
+	Endian  string
+}
+
+var templateFuncs = template.FuncMap{
+	"args":  templateArgs,
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"add":   func(a, b int) int { return a + b },
+	"div":   func(a, b int) int { return a / b },
+	"list":  func(items ...interface{}) []interface{} { return items },
+	"hex":   func(v uint32) string { return fmt.Sprintf("0x%08x", v) },
+
+	"headerLength":  func() int { return PacketHeaderLength },
+	"headerSize":    func() int { return PacketHeaderSize },
+	"headerPadding": func() int { return PacketPadding },
+	"headerFields":  func() []string { return PacketHeaderFields },
+}
+
+// templateArgs builds a map from key value pairs, so that a template can be called with several arguments.
+func templateArgs(kv ...interface{}) (map[string]interface{}, error) {
+	if len(kv)%2 != 0 {
+		return nil, fmt.Errorf("args: odd number of arguments")
+	}
+	m := make(map[string]interface{}, len(kv)/2)
+	for i := 0; i < len(kv); i += 2 {
+		key, ok := kv[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("args: key %v is not a string", kv[i])
+		}
+		m[key] = kv[i+1]
+	}
+	return m, nil
+}
+
+// loadTemplates parses the embedded templates of a language. If the option "templates"
+// names a directory, the *.tmpl files in its subdirectory named after the language, e.g.
+// <dir>/go, are parsed afterwards, so that any template they define replaces the embedded
+// one of the same name. Template names repeat across languages, which is why each language
+// has its own subdirectory.
+func loadTemplates(lang string, opts Options, funcs template.FuncMap) (*template.Template, error) {
+	t := template.New(lang).Funcs(templateFuncs).Funcs(funcs)
+	t, err := t.ParseFS(templateFS, "templates/"+lang+"/*.tmpl")
+	if err != nil {
+		return nil, err
+	}
+	if dir := opts.Get("templates", ""); len(dir) != 0 {
+		files, err := filepath.Glob(filepath.Join(dir, lang, "*.tmpl"))
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			if _, err = t.ParseFiles(file); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return t, nil
+}
+
+// snakeCase converts a Go identifier into lower snake case, e.g. PacketType
+// becomes packet_type and HTTPServer becomes http_server.
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+func executeTemplate(t *template.Template, name string, data interface{}) ([]byte, error) {
+	var buff bytes.Buffer
+	if err := t.ExecuteTemplate(&buff, name, data); err != nil {
+		return nil, err
+	}
+	return buff.Bytes(), nil
+}
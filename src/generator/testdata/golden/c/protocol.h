@@ -0,0 +1,177 @@
+/* Code generated by goproto. DO NOT EDIT. */
+#ifndef PROTOCOL_H
+#define PROTOCOL_H
+
+#include <stddef.h>
+#include <stdint.h>
+
+#ifdef __cplusplus
+extern "C" {
+#endif
+
+/* byte order of the wire format: big endian */
+
+#define PROTOCOL_OK              0
+#define PROTOCOL_ERR_OVERFLOW   -1
+#define PROTOCOL_ERR_NOMEM      -2
+#define PROTOCOL_ERR_UNKNOWN    -3
+
+/* PACKET_HEADER_LENGTH is what the header counts for in Len: PACKET_HEADER_SIZE bytes
+   of header and PACKET_PADDING reserved bytes after the fields of the packet. */
+#define PROTOCOL_PACKET_HEADER_LENGTH 36
+#define PROTOCOL_PACKET_HEADER_SIZE 24
+#define PROTOCOL_PACKET_PADDING 12
+
+#define KEEPALIVE 0x00000001u
+#define LOGIN_REQUEST 0x00000002u
+#define LOGIN_RESPONSE 0x80000002u
+#define BUDDY_LIST 0x80000003u
+
+/* protocol_buffer is a byte buffer, every read and write checks that it stays inside size. */
+typedef struct protocol_buffer {
+    uint8_t *data;
+    size_t size;
+    size_t pos;
+} protocol_buffer;
+
+void protocol_buffer_init(protocol_buffer *buf, void *data, size_t size);
+
+/* protocol_string is a length prefixed string, data is NUL terminated after decoding. */
+typedef struct protocol_string {
+    uint32_t len;
+    char *data;
+} protocol_string;
+
+typedef struct protocol_packet_header {
+    uint32_t ID;
+    uint32_t PacketType;
+    uint32_t Len;
+    uint32_t Version;
+    uint32_t Ack;
+    uint32_t Token;
+} protocol_packet_header;
+
+int protocol_packet_header_encode(const protocol_packet_header *p, protocol_buffer *buf);
+int protocol_packet_header_decode(protocol_packet_header *p, protocol_buffer *buf);
+
+typedef struct Point {
+    int32_t X;
+    int16_t Y;
+} Point;
+
+typedef struct Wrap {
+    Point P;
+    protocol_string Tag;
+} Wrap;
+
+typedef struct Keepalive {
+    protocol_packet_header header;
+} Keepalive;
+
+typedef struct LoginRequest {
+    protocol_packet_header header;
+    protocol_string UserName;
+    protocol_string Code;
+    uint32_t Age;
+    uint8_t B;
+    uint8_t U8;
+    uint16_t U16;
+    uint64_t U64;
+    int8_t I8;
+    int16_t I16;
+    int32_t I32;
+    int64_t I64;
+    Point Home;
+    Wrap W;
+    Point Pos[2];
+    uint8_t Fix[3];
+    int64_t Arr[2];
+    protocol_string Names[2];
+    struct {
+        uint32_t len;
+        uint8_t *items;
+    } Raw;
+    struct {
+        uint32_t len;
+        uint16_t *items;
+    } Nums;
+    struct {
+        uint32_t len;
+        protocol_string *items;
+    } Labels;
+    struct {
+        uint32_t len;
+        Point *items;
+    } Track;
+} LoginRequest;
+
+typedef struct LoginResponse {
+    protocol_packet_header header;
+    int32_t Result;
+    uint64_t Session;
+    protocol_string Digits;
+} LoginResponse;
+
+typedef struct BuddyList {
+    protocol_packet_header header;
+    struct {
+        uint32_t len;
+        Wrap *items;
+    } Buddies;
+} BuddyList;
+
+size_t Point_length(const Point *p);
+int Point_encode(const Point *p, protocol_buffer *buf);
+int Point_decode(Point *p, protocol_buffer *buf);
+void Point_free(Point *p);
+
+size_t Wrap_length(const Wrap *p);
+int Wrap_encode(const Wrap *p, protocol_buffer *buf);
+int Wrap_decode(Wrap *p, protocol_buffer *buf);
+void Wrap_free(Wrap *p);
+
+/* Keepalive_init zeroes the packet and sets its packet type to KEEPALIVE. */
+void Keepalive_init(Keepalive *p);
+void Keepalive_adjust_length(Keepalive *p);
+size_t Keepalive_length(const Keepalive *p);
+int Keepalive_encode(const Keepalive *p, protocol_buffer *buf);
+int Keepalive_decode(Keepalive *p, protocol_buffer *buf);
+/* Keepalive_decode_body decodes the fields after the header has been decoded. */
+int Keepalive_decode_body(Keepalive *p, protocol_buffer *buf);
+void Keepalive_free(Keepalive *p);
+
+/* LoginRequest_init zeroes the packet and sets its packet type to LOGIN_REQUEST. */
+void LoginRequest_init(LoginRequest *p);
+void LoginRequest_adjust_length(LoginRequest *p);
+size_t LoginRequest_length(const LoginRequest *p);
+int LoginRequest_encode(const LoginRequest *p, protocol_buffer *buf);
+int LoginRequest_decode(LoginRequest *p, protocol_buffer *buf);
+/* LoginRequest_decode_body decodes the fields after the header has been decoded. */
+int LoginRequest_decode_body(LoginRequest *p, protocol_buffer *buf);
+void LoginRequest_free(LoginRequest *p);
+
+/* LoginResponse_init zeroes the packet and sets its packet type to LOGIN_RESPONSE. */
+void LoginResponse_init(LoginResponse *p);
+void LoginResponse_adjust_length(LoginResponse *p);
+size_t LoginResponse_length(const LoginResponse *p);
+int LoginResponse_encode(const LoginResponse *p, protocol_buffer *buf);
+int LoginResponse_decode(LoginResponse *p, protocol_buffer *buf);
+/* LoginResponse_decode_body decodes the fields after the header has been decoded. */
+int LoginResponse_decode_body(LoginResponse *p, protocol_buffer *buf);
+void LoginResponse_free(LoginResponse *p);
+
+/* BuddyList_init zeroes the packet and sets its packet type to BUDDY_LIST. */
+void BuddyList_init(BuddyList *p);
+void BuddyList_adjust_length(BuddyList *p);
+size_t BuddyList_length(const BuddyList *p);
+int BuddyList_encode(const BuddyList *p, protocol_buffer *buf);
+int BuddyList_decode(BuddyList *p, protocol_buffer *buf);
+/* BuddyList_decode_body decodes the fields after the header has been decoded. */
+int BuddyList_decode_body(BuddyList *p, protocol_buffer *buf);
+void BuddyList_free(BuddyList *p);
+
+#ifdef __cplusplus
+}
+#endif
+
+#endif /* PROTOCOL_H */
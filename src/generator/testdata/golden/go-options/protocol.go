@@ -0,0 +1,1643 @@
+package protocol
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var ErrUnknownPacket = errors.New("unknown packet")
+
+const (
+	KEEPALIVE      = 0x00000001
+	LOGIN_REQUEST  = 0x00000002
+	LOGIN_RESPONSE = 0x80000002
+	BUDDY_LIST     = 0x80000003
+)
+
+type Packet interface {
+	GetID() uint32
+	SetID(uint32)
+	GetToken() uint32
+	SetToken(uint32)
+	GetAck() uint32
+	SetAck(uint32)
+	GetPacketType() uint32
+	Length() int
+	AdjustLength()
+	Read(stream ReadStream) error
+	Write(stream WriteStream) error
+}
+
+// Resetter is implemented by the generated packets and structs, Reset restores the value
+// New<Name>() returns. It is kept out of Packet for the implementations written by hand.
+type Resetter interface {
+	Reset()
+}
+
+// Validator is implemented by the generated packets and structs, Validate checks the fields
+// against their annotations. It is kept out of Packet for the implementations written by hand.
+type Validator interface {
+	Validate() error
+}
+
+type PacketHeader struct {
+	ID         uint32
+	PacketType uint32
+	Len        uint32
+	Version    uint32
+	Ack        uint32
+	Token      uint32
+}
+
+func (p *PacketHeader) GetID() uint32 { return p.ID }
+
+func (p *PacketHeader) SetID(id uint32) { p.ID = id }
+
+func (p *PacketHeader) GetToken() uint32 { return p.Token }
+
+func (p *PacketHeader) SetToken(token uint32) { p.Token = token }
+
+func (p *PacketHeader) GetAck() uint32 { return p.Ack }
+
+func (p *PacketHeader) SetAck(ack uint32) { p.Ack = ack }
+
+func (p *PacketHeader) GetPacketType() uint32 { return p.PacketType }
+
+// Length is what the header counts for in Len: the 24 bytes it writes and the
+// 12 reserved bytes after the fields of the packet.
+func (p *PacketHeader) Length() int { return 36 }
+
+func (p *PacketHeader) AdjustLength() { p.Len = uint32(p.Length()) }
+
+func (p *PacketHeader) Read(stream ReadStream) error {
+	var err error
+	if p.ID, err = stream.ReadUint32(); err != nil {
+		return err
+	}
+	if p.PacketType, err = stream.ReadUint32(); err != nil {
+		return err
+	}
+	if p.Len, err = stream.ReadUint32(); err != nil {
+		return err
+	}
+	if p.Version, err = stream.ReadUint32(); err != nil {
+		return err
+	}
+	if p.Ack, err = stream.ReadUint32(); err != nil {
+		return err
+	}
+	if p.Token, err = stream.ReadUint32(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (w *PacketHeader) Write(stream WriteStream) error {
+	var err error
+	if err = stream.WriteUint32(w.ID); err != nil {
+		return err
+	}
+	if err = stream.WriteUint32(w.PacketType); err != nil {
+		return err
+	}
+	if err = stream.WriteUint32(w.Len); err != nil {
+		return err
+	}
+	if err = stream.WriteUint32(w.Version); err != nil {
+		return err
+	}
+	if err = stream.WriteUint32(w.Ack); err != nil {
+		return err
+	}
+	if err = stream.WriteUint32(w.Token); err != nil {
+		return err
+	}
+	return nil
+}
+
+type Point struct {
+	X int32
+	Y int16
+}
+
+// Reset sets the fields to their defaults.
+func (s *Point) Reset() {
+	*s = Point{
+		X: -1,
+	}
+}
+
+func (s *Point) Length() int {
+	var totalLength int
+	totalLength += 4
+	totalLength += 2
+	return totalLength
+}
+
+func (s *Point) AdjustLength() {}
+
+func (s *Point) Read(stream ReadStream) error {
+	var err error
+	if val, err := stream.ReadUint32(); err != nil {
+		return err
+	} else {
+		s.X = int32(val)
+	}
+	if val, err := stream.ReadUint16(); err != nil {
+		return err
+	} else {
+		s.Y = int16(val)
+	}
+	return err
+}
+
+func (s *Point) Write(stream WriteStream) error {
+	var err error
+	if err = stream.WriteUint32(uint32(s.X)); err != nil {
+		return err
+	}
+	if err = stream.WriteUint16(uint16(s.Y)); err != nil {
+		return err
+	}
+	return err
+}
+
+func (s *Point) String() string { return s.StringWith(DefaultFormatOptions) }
+
+// StringWith returns the text form of Point, nested structs are indented and long slices are truncated as opts allows.
+func (s *Point) StringWith(opts FormatOptions) string {
+	w := textWriter{opts: &opts}
+	s.writeText(&w)
+	return w.b.String()
+}
+
+func (s *Point) writeText(w *textWriter) {
+	w.begin("Point")
+	w.field("X")
+	w.value(s.X)
+	w.field("Y")
+	w.value(s.Y)
+	w.end()
+}
+
+// Validate checks the fields against the rules given by their annotations.
+func (s *Point) Validate() error { return s.validate("Point") }
+
+func (s *Point) validate(path string) error {
+	if s.X < -100 {
+		return invalid(path+".X", "min", "value %d is less than min -100", s.X)
+	}
+	if s.X > 100 {
+		return invalid(path+".X", "max", "value %d exceeds max 100", s.X)
+	}
+	return nil
+}
+
+type Wrap struct {
+	P   Point
+	Tag string
+}
+
+// Reset sets the fields to their defaults.
+func (s *Wrap) Reset() {
+	*s = Wrap{
+		P: Point{X: -1},
+	}
+}
+
+func (s *Wrap) Length() int {
+	var totalLength int
+	totalLength += s.P.Length()
+	totalLength += 4 + len(s.Tag)
+	return totalLength
+}
+
+func (s *Wrap) AdjustLength() {}
+
+func (s *Wrap) Read(stream ReadStream) error {
+	var err error
+	if err = s.P.Read(stream); err != nil {
+		return err
+	}
+	{
+		var size uint32
+		if size, err = stream.ReadUint32(); err != nil {
+			return err
+		}
+		if err = checkBytes("Wrap.Tag", size, 8); err != nil {
+			return err
+		}
+		var buff []byte
+		if buff, err = stream.ReadBuff(int(size)); err != nil {
+			return err
+		}
+		s.Tag = string(buff)
+	}
+	return err
+}
+
+func (s *Wrap) Write(stream WriteStream) error {
+	var err error
+	if err = s.P.Write(stream); err != nil {
+		return err
+	}
+	if err = stream.WriteUint32(uint32(len(s.Tag))); err != nil {
+		return err
+	}
+	if err = stream.WriteBuff([]byte(s.Tag)); err != nil {
+		return err
+	}
+	return err
+}
+
+func (s *Wrap) String() string { return s.StringWith(DefaultFormatOptions) }
+
+// StringWith returns the text form of Wrap, nested structs are indented and long slices are truncated as opts allows.
+func (s *Wrap) StringWith(opts FormatOptions) string {
+	w := textWriter{opts: &opts}
+	s.writeText(&w)
+	return w.b.String()
+}
+
+func (s *Wrap) writeText(w *textWriter) {
+	w.begin("Wrap")
+	w.field("P")
+	s.P.writeText(w)
+	w.field("Tag")
+	w.value(s.Tag)
+	w.end()
+}
+
+// Validate checks the fields against the rules given by their annotations.
+func (s *Wrap) Validate() error { return s.validate("Wrap") }
+
+func (s *Wrap) validate(path string) error {
+	if err := s.P.validate(path + ".P"); err != nil {
+		return err
+	}
+	if len(s.Tag) > 8 {
+		return invalid(path+".Tag", "max", "length %d exceeds max 8", len(s.Tag))
+	}
+	return nil
+}
+
+type Keepalive struct {
+	PacketHeader
+}
+
+func NewKeepalive() *Keepalive {
+	return &Keepalive{
+		PacketHeader: PacketHeader{
+			PacketType: KEEPALIVE,
+		},
+	}
+}
+
+// Reset sets the fields to their defaults and clears the header except the PacketType.
+func (s *Keepalive) Reset() {
+	*s = Keepalive{
+		PacketHeader: PacketHeader{
+			PacketType: KEEPALIVE,
+		},
+	}
+}
+
+func (s *Keepalive) Length() int {
+	var totalLength int
+	totalLength += s.PacketHeader.Length()
+	return totalLength
+}
+
+func (s *Keepalive) AdjustLength() { s.PacketHeader.Len = uint32(s.Length()) }
+
+func (s *Keepalive) Read(stream ReadStream) error { return nil }
+
+func (s *Keepalive) Write(stream WriteStream) error {
+	var err error
+	if err = s.PacketHeader.Write(stream); err != nil {
+		return err
+	}
+	return err
+}
+
+// jsonKeepalive is the JSON form of Keepalive, the fields point into the packet.
+type jsonKeepalive struct {
+	Type   string        `json:"type"`
+	Header *PacketHeader `json:"header"`
+}
+
+func (s *Keepalive) jsonFields() *jsonKeepalive {
+	return &jsonKeepalive{
+		Type:   "KEEPALIVE",
+		Header: &s.PacketHeader,
+	}
+}
+
+// MarshalJSON encodes the packet as an object with the ID name as "type", the header as "header" and the fields by name.
+func (s *Keepalive) MarshalJSON() ([]byte, error) { return json.Marshal(s.jsonFields()) }
+
+// UnmarshalJSON decodes the object written by MarshalJSON, fields missing in data are left unchanged.
+func (s *Keepalive) UnmarshalJSON(data []byte) error {
+	v := s.jsonFields()
+	v.Type = ""
+	if err := json.Unmarshal(data, v); err != nil {
+		return err
+	}
+	if len(v.Type) != 0 && v.Type != "KEEPALIVE" {
+		return fmt.Errorf("json: packet type %q is not KEEPALIVE", v.Type)
+	}
+	s.PacketType = KEEPALIVE
+	return nil
+}
+
+func (s *Keepalive) String() string { return s.StringWith(DefaultFormatOptions) }
+
+// StringWith returns the text form of Keepalive, nested structs are indented and long slices are truncated as opts allows.
+func (s *Keepalive) StringWith(opts FormatOptions) string {
+	w := textWriter{opts: &opts}
+	s.writeText(&w)
+	return w.b.String()
+}
+
+func (s *Keepalive) writeText(w *textWriter) {
+	w.begin("Keepalive")
+	w.field("Header")
+	s.PacketHeader.writeText(w)
+	w.end()
+}
+
+// Validate checks the fields against the rules given by their annotations.
+func (s *Keepalive) Validate() error { return s.validate("Keepalive") }
+
+func (s *Keepalive) validate(path string) error {
+	return nil
+}
+
+type LoginRequest struct {
+	PacketHeader
+	UserName string
+	Code     string
+	Age      uint32
+	B        byte
+	U8       uint8
+	U16      uint16
+	U64      uint64
+	I8       int8
+	I16      int16
+	I32      int32
+	I64      int64
+	Home     Point
+	W        Wrap
+	Pos      [2]Point
+	Fix      [3]byte
+	Arr      [2]int64
+	Names    [2]string
+	Raw      []byte
+	Nums     []uint16
+	Labels   []string
+	Track    []Point
+}
+
+func NewLoginRequest() *LoginRequest {
+	return &LoginRequest{
+		PacketHeader: PacketHeader{
+			PacketType: LOGIN_REQUEST,
+		},
+		UserName: "guest",
+		Age:      18,
+		Home:     Point{X: -1},
+		W:        Wrap{P: Point{X: -1}},
+		Pos:      [2]Point{{X: -1}, {X: -1}},
+	}
+}
+
+// Reset sets the fields to their defaults and clears the header except the PacketType.
+func (s *LoginRequest) Reset() {
+	*s = LoginRequest{
+		PacketHeader: PacketHeader{
+			PacketType: LOGIN_REQUEST,
+		},
+		UserName: "guest",
+		Age:      18,
+		Home:     Point{X: -1},
+		W:        Wrap{P: Point{X: -1}},
+		Pos:      [2]Point{{X: -1}, {X: -1}},
+	}
+}
+
+func (s *LoginRequest) Length() int {
+	var totalLength int
+	totalLength += s.PacketHeader.Length()
+	totalLength += 4 + len(s.UserName)
+	totalLength += 4 + len(s.Code)
+	totalLength += 4
+	totalLength += 1
+	totalLength += 1
+	totalLength += 2
+	totalLength += 8
+	totalLength += 1
+	totalLength += 2
+	totalLength += 4
+	totalLength += 8
+	totalLength += s.Home.Length()
+	totalLength += s.W.Length()
+	for i := range s.Pos {
+		totalLength += s.Pos[i].Length()
+	}
+	totalLength += len(s.Fix) * 1
+	totalLength += len(s.Arr) * 8
+	for i := range s.Names {
+		totalLength += 4 + len(s.Names[i])
+	}
+	totalLength += 4
+	totalLength += len(s.Raw) * 1
+	totalLength += 4
+	totalLength += len(s.Nums) * 2
+	totalLength += 4
+	for i := range s.Labels {
+		totalLength += 4 + len(s.Labels[i])
+	}
+	totalLength += 4
+	for i := range s.Track {
+		totalLength += s.Track[i].Length()
+	}
+	return totalLength
+}
+
+func (s *LoginRequest) AdjustLength() { s.PacketHeader.Len = uint32(s.Length()) }
+
+func (s *LoginRequest) Read(stream ReadStream) error {
+	var err error
+	{
+		var size uint32
+		if size, err = stream.ReadUint32(); err != nil {
+			return err
+		}
+		if err = checkBytes("LoginRequest.UserName", size, 16); err != nil {
+			return err
+		}
+		var buff []byte
+		if buff, err = stream.ReadBuff(int(size)); err != nil {
+			return err
+		}
+		s.UserName = string(buff)
+	}
+	{
+		var size uint32
+		if size, err = stream.ReadUint32(); err != nil {
+			return err
+		}
+		if err = checkBytes("LoginRequest.Code", size, -1); err != nil {
+			return err
+		}
+		var buff []byte
+		if buff, err = stream.ReadBuff(int(size)); err != nil {
+			return err
+		}
+		s.Code = string(buff)
+	}
+	if s.Age, err = stream.ReadUint32(); err != nil {
+		return err
+	}
+	if s.B, err = stream.ReadByte(); err != nil {
+		return err
+	}
+	if s.U8, err = stream.ReadByte(); err != nil {
+		return err
+	}
+	if s.U16, err = stream.ReadUint16(); err != nil {
+		return err
+	}
+	if s.U64, err = stream.ReadUint64(); err != nil {
+		return err
+	}
+	if val, err := stream.ReadByte(); err != nil {
+		return err
+	} else {
+		s.I8 = int8(val)
+	}
+	if val, err := stream.ReadUint16(); err != nil {
+		return err
+	} else {
+		s.I16 = int16(val)
+	}
+	if val, err := stream.ReadUint32(); err != nil {
+		return err
+	} else {
+		s.I32 = int32(val)
+	}
+	if val, err := stream.ReadUint64(); err != nil {
+		return err
+	} else {
+		s.I64 = int64(val)
+	}
+	if err = s.Home.Read(stream); err != nil {
+		return err
+	}
+	if err = s.W.Read(stream); err != nil {
+		return err
+	}
+	for i := range s.Pos {
+		if err = s.Pos[i].Read(stream); err != nil {
+			return err
+		}
+	}
+	for i := range s.Fix {
+		if s.Fix[i], err = stream.ReadByte(); err != nil {
+			return err
+		}
+	}
+	for i := range s.Arr {
+		if val, err := stream.ReadUint64(); err != nil {
+			return err
+		} else {
+			s.Arr[i] = int64(val)
+		}
+	}
+	for i := range s.Names {
+		{
+			var size uint32
+			if size, err = stream.ReadUint32(); err != nil {
+				return err
+			}
+			if err = checkBytes("LoginRequest.Names", size, -1); err != nil {
+				return err
+			}
+			var buff []byte
+			if buff, err = stream.ReadBuff(int(size)); err != nil {
+				return err
+			}
+			s.Names[i] = string(buff)
+		}
+	}
+	{
+		var size uint32
+		if size, err = stream.ReadUint32(); err != nil {
+			return err
+		}
+		if err = checkBytes("LoginRequest.Raw", size, 32); err != nil {
+			return err
+		}
+		if s.Raw, err = stream.ReadBuff(int(size)); err != nil {
+			return err
+		}
+	}
+	{
+		var size uint32
+		if size, err = stream.ReadUint32(); err != nil {
+			return err
+		}
+		if err = checkCount("LoginRequest.Nums", size, 4, 2, stream); err != nil {
+			return err
+		}
+		s.Nums = make([]uint16, size)
+		for i := range s.Nums {
+			if s.Nums[i], err = stream.ReadUint16(); err != nil {
+				return err
+			}
+		}
+	}
+	{
+		var size uint32
+		if size, err = stream.ReadUint32(); err != nil {
+			return err
+		}
+		if err = checkCount("LoginRequest.Labels", size, -1, 4, stream); err != nil {
+			return err
+		}
+		s.Labels = make([]string, size)
+		for i := range s.Labels {
+			{
+				var size uint32
+				if size, err = stream.ReadUint32(); err != nil {
+					return err
+				}
+				if err = checkBytes("LoginRequest.Labels", size, -1); err != nil {
+					return err
+				}
+				var buff []byte
+				if buff, err = stream.ReadBuff(int(size)); err != nil {
+					return err
+				}
+				s.Labels[i] = string(buff)
+			}
+		}
+	}
+	{
+		var size uint32
+		if size, err = stream.ReadUint32(); err != nil {
+			return err
+		}
+		if err = checkCount("LoginRequest.Track", size, 8, 6, stream); err != nil {
+			return err
+		}
+		s.Track = make([]Point, size)
+		for i := range s.Track {
+			if err = s.Track[i].Read(stream); err != nil {
+				return err
+			}
+		}
+	}
+	return err
+}
+
+func (s *LoginRequest) Write(stream WriteStream) error {
+	var err error
+	if err = s.PacketHeader.Write(stream); err != nil {
+		return err
+	}
+	if err = stream.WriteUint32(uint32(len(s.UserName))); err != nil {
+		return err
+	}
+	if err = stream.WriteBuff([]byte(s.UserName)); err != nil {
+		return err
+	}
+	if err = stream.WriteUint32(uint32(len(s.Code))); err != nil {
+		return err
+	}
+	if err = stream.WriteBuff([]byte(s.Code)); err != nil {
+		return err
+	}
+	if err = stream.WriteUint32(s.Age); err != nil {
+		return err
+	}
+	if err = stream.WriteByte(s.B); err != nil {
+		return err
+	}
+	if err = stream.WriteByte(s.U8); err != nil {
+		return err
+	}
+	if err = stream.WriteUint16(s.U16); err != nil {
+		return err
+	}
+	if err = stream.WriteUint64(s.U64); err != nil {
+		return err
+	}
+	if err = stream.WriteByte(byte(s.I8)); err != nil {
+		return err
+	}
+	if err = stream.WriteUint16(uint16(s.I16)); err != nil {
+		return err
+	}
+	if err = stream.WriteUint32(uint32(s.I32)); err != nil {
+		return err
+	}
+	if err = stream.WriteUint64(uint64(s.I64)); err != nil {
+		return err
+	}
+	if err = s.Home.Write(stream); err != nil {
+		return err
+	}
+	if err = s.W.Write(stream); err != nil {
+		return err
+	}
+	for i := range s.Pos {
+		if err = s.Pos[i].Write(stream); err != nil {
+			return err
+		}
+	}
+	for i := range s.Fix {
+		if err = stream.WriteByte(s.Fix[i]); err != nil {
+			return err
+		}
+	}
+	for i := range s.Arr {
+		if err = stream.WriteUint64(uint64(s.Arr[i])); err != nil {
+			return err
+		}
+	}
+	for i := range s.Names {
+		if err = stream.WriteUint32(uint32(len(s.Names[i]))); err != nil {
+			return err
+		}
+		if err = stream.WriteBuff([]byte(s.Names[i])); err != nil {
+			return err
+		}
+	}
+	if err = stream.WriteUint32(uint32(len(s.Raw))); err != nil {
+		return err
+	}
+	if err = stream.WriteBuff(s.Raw); err != nil {
+		return err
+	}
+	if err = stream.WriteUint32(uint32(len(s.Nums))); err != nil {
+		return err
+	}
+	for i := range s.Nums {
+		if err = stream.WriteUint16(s.Nums[i]); err != nil {
+			return err
+		}
+	}
+	if err = stream.WriteUint32(uint32(len(s.Labels))); err != nil {
+		return err
+	}
+	for i := range s.Labels {
+		if err = stream.WriteUint32(uint32(len(s.Labels[i]))); err != nil {
+			return err
+		}
+		if err = stream.WriteBuff([]byte(s.Labels[i])); err != nil {
+			return err
+		}
+	}
+	if err = stream.WriteUint32(uint32(len(s.Track))); err != nil {
+		return err
+	}
+	for i := range s.Track {
+		if err = s.Track[i].Write(stream); err != nil {
+			return err
+		}
+	}
+	return err
+}
+
+// jsonLoginRequest is the JSON form of LoginRequest, the fields point into the packet.
+type jsonLoginRequest struct {
+	Type     string        `json:"type"`
+	Header   *PacketHeader `json:"header"`
+	UserName *string
+	Code     *string
+	Age      *uint32
+	B        *byte
+	U8       *uint8
+	U16      *uint16
+	U64      *uint64
+	I8       *int8
+	I16      *int16
+	I32      *int32
+	I64      *int64
+	Home     *Point
+	W        *Wrap
+	Pos      *[2]Point
+	Fix      *[3]byte
+	Arr      *[2]int64
+	Names    *[2]string
+	Raw      *[]byte
+	Nums     *[]uint16
+	Labels   *[]string
+	Track    *[]Point
+}
+
+func (s *LoginRequest) jsonFields() *jsonLoginRequest {
+	return &jsonLoginRequest{
+		Type:     "LOGIN_REQUEST",
+		Header:   &s.PacketHeader,
+		UserName: &s.UserName,
+		Code:     &s.Code,
+		Age:      &s.Age,
+		B:        &s.B,
+		U8:       &s.U8,
+		U16:      &s.U16,
+		U64:      &s.U64,
+		I8:       &s.I8,
+		I16:      &s.I16,
+		I32:      &s.I32,
+		I64:      &s.I64,
+		Home:     &s.Home,
+		W:        &s.W,
+		Pos:      &s.Pos,
+		Fix:      &s.Fix,
+		Arr:      &s.Arr,
+		Names:    &s.Names,
+		Raw:      &s.Raw,
+		Nums:     &s.Nums,
+		Labels:   &s.Labels,
+		Track:    &s.Track,
+	}
+}
+
+// MarshalJSON encodes the packet as an object with the ID name as "type", the header as "header" and the fields by name.
+func (s *LoginRequest) MarshalJSON() ([]byte, error) { return json.Marshal(s.jsonFields()) }
+
+// UnmarshalJSON decodes the object written by MarshalJSON, fields missing in data are left unchanged.
+func (s *LoginRequest) UnmarshalJSON(data []byte) error {
+	v := s.jsonFields()
+	v.Type = ""
+	if err := json.Unmarshal(data, v); err != nil {
+		return err
+	}
+	if len(v.Type) != 0 && v.Type != "LOGIN_REQUEST" {
+		return fmt.Errorf("json: packet type %q is not LOGIN_REQUEST", v.Type)
+	}
+	s.PacketType = LOGIN_REQUEST
+	return nil
+}
+
+func (s *LoginRequest) String() string { return s.StringWith(DefaultFormatOptions) }
+
+// StringWith returns the text form of LoginRequest, nested structs are indented and long slices are truncated as opts allows.
+func (s *LoginRequest) StringWith(opts FormatOptions) string {
+	w := textWriter{opts: &opts}
+	s.writeText(&w)
+	return w.b.String()
+}
+
+func (s *LoginRequest) writeText(w *textWriter) {
+	w.begin("LoginRequest")
+	w.field("Header")
+	s.PacketHeader.writeText(w)
+	w.field("UserName")
+	w.value(s.UserName)
+	w.field("Code")
+	w.value(s.Code)
+	w.field("Age")
+	w.value(s.Age)
+	w.field("B")
+	w.value(s.B)
+	w.field("U8")
+	w.value(s.U8)
+	w.field("U16")
+	w.value(s.U16)
+	w.field("U64")
+	w.value(s.U64)
+	w.field("I8")
+	w.value(s.I8)
+	w.field("I16")
+	w.value(s.I16)
+	w.field("I32")
+	w.value(s.I32)
+	w.field("I64")
+	w.value(s.I64)
+	w.field("Home")
+	s.Home.writeText(w)
+	w.field("W")
+	s.W.writeText(w)
+	w.field("Pos")
+	w.list(len(s.Pos), true, func(i int) { s.Pos[i].writeText(w) })
+	w.field("Fix")
+	w.bytes(s.Fix[:])
+	w.field("Arr")
+	w.list(len(s.Arr), false, func(i int) { w.value(s.Arr[i]) })
+	w.field("Names")
+	w.list(len(s.Names), false, func(i int) { w.value(s.Names[i]) })
+	w.field("Raw")
+	w.bytes(s.Raw)
+	w.field("Nums")
+	w.list(len(s.Nums), false, func(i int) { w.value(s.Nums[i]) })
+	w.field("Labels")
+	w.list(len(s.Labels), false, func(i int) { w.value(s.Labels[i]) })
+	w.field("Track")
+	w.list(len(s.Track), true, func(i int) { s.Track[i].writeText(w) })
+	w.end()
+}
+
+// Validate checks the fields against the rules given by their annotations.
+func (s *LoginRequest) Validate() error { return s.validate("LoginRequest") }
+
+func (s *LoginRequest) validate(path string) error {
+	if len(s.UserName) == 0 {
+		return invalid(path+".UserName", "required", "must not be empty")
+	}
+	if len(s.UserName) > 16 {
+		return invalid(path+".UserName", "max", "length %d exceeds max 16", len(s.UserName))
+	}
+	if !pattern0.MatchString(s.UserName) {
+		return invalid(path+".UserName", "pattern", "%q does not match %s", s.UserName, pattern0)
+	}
+	if len(s.Code) != 4 {
+		return invalid(path+".Code", "len", "length %d is not 4", len(s.Code))
+	}
+	if s.Age < 1 {
+		return invalid(path+".Age", "min", "value %d is less than min 1", s.Age)
+	}
+	if s.Age > 200 {
+		return invalid(path+".Age", "max", "value %d exceeds max 200", s.Age)
+	}
+	if err := s.Home.validate(path + ".Home"); err != nil {
+		return err
+	}
+	if err := s.W.validate(path + ".W"); err != nil {
+		return err
+	}
+	for i := range s.Pos {
+		if err := s.Pos[i].validate(fmt.Sprintf("%s.Pos[%d]", path, i)); err != nil {
+			return err
+		}
+	}
+	if len(s.Raw) > 32 {
+		return invalid(path+".Raw", "max", "length %d exceeds max 32", len(s.Raw))
+	}
+	if len(s.Nums) < 1 {
+		return invalid(path+".Nums", "min", "length %d is less than min 1", len(s.Nums))
+	}
+	if len(s.Nums) > 4 {
+		return invalid(path+".Nums", "max", "length %d exceeds max 4", len(s.Nums))
+	}
+	if len(s.Track) > 8 {
+		return invalid(path+".Track", "max", "length %d exceeds max 8", len(s.Track))
+	}
+	for i := range s.Track {
+		if err := s.Track[i].validate(fmt.Sprintf("%s.Track[%d]", path, i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type LoginResponse struct {
+	PacketHeader
+	Result  int32
+	Session uint64
+	Digits  string
+}
+
+func NewLoginResponse() *LoginResponse {
+	return &LoginResponse{
+		PacketHeader: PacketHeader{
+			PacketType: LOGIN_RESPONSE,
+		},
+		Digits: "000000000000",
+	}
+}
+
+// Reset sets the fields to their defaults and clears the header except the PacketType.
+func (s *LoginResponse) Reset() {
+	*s = LoginResponse{
+		PacketHeader: PacketHeader{
+			PacketType: LOGIN_RESPONSE,
+		},
+		Digits: "000000000000",
+	}
+}
+
+func (s *LoginResponse) Length() int {
+	var totalLength int
+	totalLength += s.PacketHeader.Length()
+	totalLength += 4
+	totalLength += 8
+	totalLength += 4 + len(s.Digits)
+	return totalLength
+}
+
+func (s *LoginResponse) AdjustLength() { s.PacketHeader.Len = uint32(s.Length()) }
+
+func (s *LoginResponse) Read(stream ReadStream) error {
+	var err error
+	if val, err := stream.ReadUint32(); err != nil {
+		return err
+	} else {
+		s.Result = int32(val)
+	}
+	if s.Session, err = stream.ReadUint64(); err != nil {
+		return err
+	}
+	{
+		var size uint32
+		if size, err = stream.ReadUint32(); err != nil {
+			return err
+		}
+		if err = checkBytes("LoginResponse.Digits", size, -1); err != nil {
+			return err
+		}
+		var buff []byte
+		if buff, err = stream.ReadBuff(int(size)); err != nil {
+			return err
+		}
+		s.Digits = string(buff)
+	}
+	return err
+}
+
+func (s *LoginResponse) Write(stream WriteStream) error {
+	var err error
+	if err = s.PacketHeader.Write(stream); err != nil {
+		return err
+	}
+	if err = stream.WriteUint32(uint32(s.Result)); err != nil {
+		return err
+	}
+	if err = stream.WriteUint64(s.Session); err != nil {
+		return err
+	}
+	if err = stream.WriteUint32(uint32(len(s.Digits))); err != nil {
+		return err
+	}
+	if err = stream.WriteBuff([]byte(s.Digits)); err != nil {
+		return err
+	}
+	return err
+}
+
+// jsonLoginResponse is the JSON form of LoginResponse, the fields point into the packet.
+type jsonLoginResponse struct {
+	Type    string        `json:"type"`
+	Header  *PacketHeader `json:"header"`
+	Result  *int32
+	Session *uint64
+	Digits  *string
+}
+
+func (s *LoginResponse) jsonFields() *jsonLoginResponse {
+	return &jsonLoginResponse{
+		Type:    "LOGIN_RESPONSE",
+		Header:  &s.PacketHeader,
+		Result:  &s.Result,
+		Session: &s.Session,
+		Digits:  &s.Digits,
+	}
+}
+
+// MarshalJSON encodes the packet as an object with the ID name as "type", the header as "header" and the fields by name.
+func (s *LoginResponse) MarshalJSON() ([]byte, error) { return json.Marshal(s.jsonFields()) }
+
+// UnmarshalJSON decodes the object written by MarshalJSON, fields missing in data are left unchanged.
+func (s *LoginResponse) UnmarshalJSON(data []byte) error {
+	v := s.jsonFields()
+	v.Type = ""
+	if err := json.Unmarshal(data, v); err != nil {
+		return err
+	}
+	if len(v.Type) != 0 && v.Type != "LOGIN_RESPONSE" {
+		return fmt.Errorf("json: packet type %q is not LOGIN_RESPONSE", v.Type)
+	}
+	s.PacketType = LOGIN_RESPONSE
+	return nil
+}
+
+func (s *LoginResponse) String() string { return s.StringWith(DefaultFormatOptions) }
+
+// StringWith returns the text form of LoginResponse, nested structs are indented and long slices are truncated as opts allows.
+func (s *LoginResponse) StringWith(opts FormatOptions) string {
+	w := textWriter{opts: &opts}
+	s.writeText(&w)
+	return w.b.String()
+}
+
+func (s *LoginResponse) writeText(w *textWriter) {
+	w.begin("LoginResponse")
+	w.field("Header")
+	s.PacketHeader.writeText(w)
+	w.field("Result")
+	w.value(s.Result)
+	w.field("Session")
+	w.value(s.Session)
+	w.field("Digits")
+	w.value(s.Digits)
+	w.end()
+}
+
+// Validate checks the fields against the rules given by their annotations.
+func (s *LoginResponse) Validate() error { return s.validate("LoginResponse") }
+
+func (s *LoginResponse) validate(path string) error {
+	if len(s.Digits) != 12 {
+		return invalid(path+".Digits", "len", "length %d is not 12", len(s.Digits))
+	}
+	if !pattern1.MatchString(s.Digits) {
+		return invalid(path+".Digits", "pattern", "%q does not match %s", s.Digits, pattern1)
+	}
+	return nil
+}
+
+type BuddyList struct {
+	PacketHeader
+	Buddies []Wrap
+}
+
+func NewBuddyList() *BuddyList {
+	return &BuddyList{
+		PacketHeader: PacketHeader{
+			PacketType: BUDDY_LIST,
+		},
+	}
+}
+
+// Reset sets the fields to their defaults and clears the header except the PacketType.
+func (s *BuddyList) Reset() {
+	*s = BuddyList{
+		PacketHeader: PacketHeader{
+			PacketType: BUDDY_LIST,
+		},
+	}
+}
+
+func (s *BuddyList) Length() int {
+	var totalLength int
+	totalLength += s.PacketHeader.Length()
+	totalLength += 4
+	for i := range s.Buddies {
+		totalLength += s.Buddies[i].Length()
+	}
+	return totalLength
+}
+
+func (s *BuddyList) AdjustLength() { s.PacketHeader.Len = uint32(s.Length()) }
+
+func (s *BuddyList) Read(stream ReadStream) error {
+	var err error
+	{
+		var size uint32
+		if size, err = stream.ReadUint32(); err != nil {
+			return err
+		}
+		if err = checkCount("BuddyList.Buddies", size, -1, 10, stream); err != nil {
+			return err
+		}
+		s.Buddies = make([]Wrap, size)
+		for i := range s.Buddies {
+			if err = s.Buddies[i].Read(stream); err != nil {
+				return err
+			}
+		}
+	}
+	return err
+}
+
+func (s *BuddyList) Write(stream WriteStream) error {
+	var err error
+	if err = s.PacketHeader.Write(stream); err != nil {
+		return err
+	}
+	if err = stream.WriteUint32(uint32(len(s.Buddies))); err != nil {
+		return err
+	}
+	for i := range s.Buddies {
+		if err = s.Buddies[i].Write(stream); err != nil {
+			return err
+		}
+	}
+	return err
+}
+
+// jsonBuddyList is the JSON form of BuddyList, the fields point into the packet.
+type jsonBuddyList struct {
+	Type    string        `json:"type"`
+	Header  *PacketHeader `json:"header"`
+	Buddies *[]Wrap
+}
+
+func (s *BuddyList) jsonFields() *jsonBuddyList {
+	return &jsonBuddyList{
+		Type:    "BUDDY_LIST",
+		Header:  &s.PacketHeader,
+		Buddies: &s.Buddies,
+	}
+}
+
+// MarshalJSON encodes the packet as an object with the ID name as "type", the header as "header" and the fields by name.
+func (s *BuddyList) MarshalJSON() ([]byte, error) { return json.Marshal(s.jsonFields()) }
+
+// UnmarshalJSON decodes the object written by MarshalJSON, fields missing in data are left unchanged.
+func (s *BuddyList) UnmarshalJSON(data []byte) error {
+	v := s.jsonFields()
+	v.Type = ""
+	if err := json.Unmarshal(data, v); err != nil {
+		return err
+	}
+	if len(v.Type) != 0 && v.Type != "BUDDY_LIST" {
+		return fmt.Errorf("json: packet type %q is not BUDDY_LIST", v.Type)
+	}
+	s.PacketType = BUDDY_LIST
+	return nil
+}
+
+func (s *BuddyList) String() string { return s.StringWith(DefaultFormatOptions) }
+
+// StringWith returns the text form of BuddyList, nested structs are indented and long slices are truncated as opts allows.
+func (s *BuddyList) StringWith(opts FormatOptions) string {
+	w := textWriter{opts: &opts}
+	s.writeText(&w)
+	return w.b.String()
+}
+
+func (s *BuddyList) writeText(w *textWriter) {
+	w.begin("BuddyList")
+	w.field("Header")
+	s.PacketHeader.writeText(w)
+	w.field("Buddies")
+	w.list(len(s.Buddies), true, func(i int) { s.Buddies[i].writeText(w) })
+	w.end()
+}
+
+// Validate checks the fields against the rules given by their annotations.
+func (s *BuddyList) Validate() error { return s.validate("BuddyList") }
+
+func (s *BuddyList) validate(path string) error {
+	for i := range s.Buddies {
+		if err := s.Buddies[i].validate(fmt.Sprintf("%s.Buddies[%d]", path, i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type PacketCacher interface {
+	Get(id uint32, header *PacketHeader) Packet
+	Put(id uint32, packet Packet)
+}
+
+type PacketFactory struct {
+	Cacher PacketCacher
+	// Validate makes CreatePacket validate the packets it reads which implement Validator.
+	Validate bool
+}
+
+func NewPacketFactory(cacher PacketCacher) *PacketFactory {
+	return &PacketFactory{
+		Cacher: cacher,
+	}
+}
+
+func (p *PacketFactory) CreatePacket(stream ReadStream) (newPacket Packet, err error) {
+	var header PacketHeader
+	if err = header.Read(stream); err != nil {
+		return nil, err
+	}
+	if Limits.MaxTotalBytes > 0 && uint64(header.Len) > uint64(Limits.MaxTotalBytes) {
+		return nil, &LimitError{Field: "PacketHeader.Len", Length: header.Len, Limit: "MaxTotalBytes", Max: Limits.MaxTotalBytes}
+	}
+	if p.Cacher != nil {
+		newPacket = p.Cacher.Get(header.PacketType, &header)
+	}
+	if newPacket == nil {
+		switch header.PacketType {
+		case KEEPALIVE:
+			newPacket = &Keepalive{PacketHeader: header}
+		case LOGIN_REQUEST:
+			newPacket = &LoginRequest{PacketHeader: header}
+		case LOGIN_RESPONSE:
+			newPacket = &LoginResponse{PacketHeader: header}
+		case BUDDY_LIST:
+			newPacket = &BuddyList{PacketHeader: header}
+		default:
+			return nil, ErrUnknownPacket
+		}
+	}
+	if err = newPacket.Read(stream); err != nil {
+		return nil, err
+	}
+	if v, ok := newPacket.(Validator); ok && p.Validate {
+		if err = v.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	return newPacket, nil
+}
+
+// DecodeLimits bounds the lengths Read accepts from the stream before it allocates, 0 disables a limit.
+type DecodeLimits struct {
+	// MaxElements limits the element count of slices other than byte slices.
+	MaxElements int
+	// MaxStringBytes limits the length of strings and byte slices.
+	MaxStringBytes int
+	// MaxTotalBytes limits the Len in the header of a packet created by a PacketFactory.
+	MaxTotalBytes int
+}
+
+// Limits are checked by the Read methods in addition to the max annotations of the fields.
+// Regardless of them, a slice is never allocated with more elements than the data left can hold.
+var Limits = DecodeLimits{MaxElements: 1 << 20, MaxStringBytes: 1 << 24, MaxTotalBytes: 1 << 26}
+
+// LimitError reports a length read from the stream which exceeds a limit.
+type LimitError struct {
+	// Field is the field name qualified by its packet or struct, e.g. LoginRequest.UserName.
+	Field  string
+	Length uint32
+	// Limit names the exceeded limit: max for the annotation of the field, a field of
+	// DecodeLimits, or Left if the data left can't hold the elements.
+	Limit string
+	Max   int
+}
+
+func (e *LimitError) Error() string {
+	if e.Limit == "Left" {
+		return fmt.Sprintf("%s: %d elements need more than the %d bytes left", e.Field, e.Length, e.Max)
+	}
+	return fmt.Sprintf("%s: length %d exceeds %s %d", e.Field, e.Length, e.Limit, e.Max)
+}
+
+// checkCount checks the element count of a slice before it is allocated. max is the annotation
+// of the field, -1 if there is none, and minSize is the least wire size of an element.
+func checkCount(field string, count uint32, max, minSize int, stream ReadStream) error {
+	switch {
+	case max >= 0 && uint64(count) > uint64(max):
+		return &LimitError{Field: field, Length: count, Limit: "max", Max: max}
+	case Limits.MaxElements > 0 && uint64(count) > uint64(Limits.MaxElements):
+		return &LimitError{Field: field, Length: count, Limit: "MaxElements", Max: Limits.MaxElements}
+	case uint64(count)*uint64(minSize) > uint64(stream.Left()):
+		return &LimitError{Field: field, Length: count, Limit: "Left", Max: stream.Left()}
+	}
+	return nil
+}
+
+// checkBytes checks the length of a string or byte slice before it is read,
+// max is the annotation of the field, -1 if there is none.
+func checkBytes(field string, length uint32, max int) error {
+	switch {
+	case max >= 0 && uint64(length) > uint64(max):
+		return &LimitError{Field: field, Length: length, Limit: "max", Max: max}
+	case Limits.MaxStringBytes > 0 && uint64(length) > uint64(Limits.MaxStringBytes):
+		return &LimitError{Field: field, Length: length, Limit: "MaxStringBytes", Max: Limits.MaxStringBytes}
+	}
+	return nil
+}
+
+// PacketFromJSON creates the packet named by the "type" member of a JSON object and decodes the object into it.
+// The type is compared exactly with the upper case ID name MarshalJSON writes.
+// The header length is adjusted, so that the packet can be written as it is.
+func PacketFromJSON(data []byte) (Packet, error) {
+	var v struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	var packet Packet
+	switch v.Type {
+	case "KEEPALIVE":
+		packet = NewKeepalive()
+	case "LOGIN_REQUEST":
+		packet = NewLoginRequest()
+	case "LOGIN_RESPONSE":
+		packet = NewLoginResponse()
+	case "BUDDY_LIST":
+		packet = NewBuddyList()
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownPacket, v.Type)
+	}
+	if err := json.Unmarshal(data, packet); err != nil {
+		return nil, err
+	}
+	packet.AdjustLength()
+	return packet, nil
+}
+
+// FormatOptions controls the text written by StringWith.
+type FormatOptions struct {
+	// Indent is repeated once per nesting level of structs and struct slices.
+	Indent string
+	// MaxElements limits the elements written of a slice or array, 0 writes all of them.
+	MaxElements int
+	// MaxBytes limits the bytes written of a byte slice or byte array, 0 writes all of them.
+	MaxBytes int
+}
+
+// DefaultFormatOptions are the options of the String methods.
+var DefaultFormatOptions = FormatOptions{Indent: "  ", MaxElements: 16, MaxBytes: 64}
+
+// PacketTypeName returns the ID name of a packet type, or the type in hex if it is unknown.
+func PacketTypeName(packetType uint32) string {
+	switch packetType {
+	case KEEPALIVE:
+		return "KEEPALIVE"
+	case LOGIN_REQUEST:
+		return "LOGIN_REQUEST"
+	case LOGIN_RESPONSE:
+		return "LOGIN_RESPONSE"
+	case BUDDY_LIST:
+		return "BUDDY_LIST"
+	}
+	return fmt.Sprintf("0x%08x", packetType)
+}
+
+// textWriter writes the text form of packets, structs are written over several lines.
+type textWriter struct {
+	opts  *FormatOptions
+	b     strings.Builder
+	depth int
+	empty bool
+}
+
+func (w *textWriter) begin(name string) {
+	w.b.WriteString(name)
+	w.b.WriteString(" {")
+	w.depth++
+	w.empty = true
+}
+
+func (w *textWriter) newline() {
+	w.b.WriteByte('\n')
+	for i := 0; i < w.depth; i++ {
+		w.b.WriteString(w.opts.Indent)
+	}
+}
+
+func (w *textWriter) field(name string) {
+	w.newline()
+	w.b.WriteString(name)
+	w.b.WriteString(": ")
+	w.empty = false
+}
+
+func (w *textWriter) end() {
+	w.depth--
+	if !w.empty {
+		w.newline()
+	}
+	w.b.WriteByte('}')
+	w.empty = false
+}
+
+func (w *textWriter) value(v interface{}) {
+	if s, ok := v.(string); ok {
+		w.b.WriteString(strconv.Quote(s))
+	} else {
+		fmt.Fprint(&w.b, v)
+	}
+}
+
+func (w *textWriter) bytes(b []byte) {
+	n := len(b)
+	if n == 0 {
+		w.b.WriteString("(0 bytes)")
+		return
+	}
+	if w.opts.MaxBytes > 0 && n > w.opts.MaxBytes {
+		b = b[:w.opts.MaxBytes]
+	}
+	w.b.WriteString(hex.EncodeToString(b))
+	if len(b) < n {
+		w.b.WriteString("...")
+	}
+	fmt.Fprintf(&w.b, " (%d bytes)", n)
+}
+
+// list writes n elements by calling each, struct elements are written one per line.
+func (w *textWriter) list(n int, structs bool, each func(i int)) {
+	shown := n
+	if w.opts.MaxElements > 0 && n > w.opts.MaxElements {
+		shown = w.opts.MaxElements
+	}
+	w.b.WriteByte('[')
+	if structs {
+		w.depth++
+	}
+	for i := 0; i < shown; i++ {
+		if structs {
+			w.newline()
+		} else if i > 0 {
+			w.b.WriteString(", ")
+		}
+		each(i)
+	}
+	if shown < n {
+		if structs {
+			w.newline()
+		} else if shown > 0 {
+			w.b.WriteString(", ")
+		}
+		fmt.Fprintf(&w.b, "... (%d elements)", n)
+	}
+	if structs {
+		w.depth--
+		if n > 0 {
+			w.newline()
+		}
+	}
+	w.b.WriteByte(']')
+}
+
+func (p *PacketHeader) writeText(w *textWriter) {
+	fmt.Fprintf(&w.b, "{ID: %d, PacketType: %s, Len: %d, Version: %d, Ack: %d, Token: %d}",
+		p.ID, PacketTypeName(p.PacketType), p.Len, p.Version, p.Ack, p.Token)
+}
+
+func (p *PacketHeader) String() string {
+	w := textWriter{opts: &DefaultFormatOptions}
+	p.writeText(&w)
+	return w.b.String()
+}
+
+// ValidationError reports a field which breaks a rule given by its annotations.
+type ValidationError struct {
+	// Field is the path of the field, e.g. LoginRequest.Pos[1].X.
+	Field string
+	// Rule is the broken annotation: min, max, len, pattern or required.
+	Rule   string
+	Reason string
+}
+
+func (e *ValidationError) Error() string { return e.Field + ": " + e.Reason }
+
+func invalid(field, rule, format string, args ...interface{}) error {
+	return &ValidationError{Field: field, Rule: rule, Reason: fmt.Sprintf(format, args...)}
+}
+
+// pattern0 is the pattern of LoginRequest.UserName.
+var pattern0 = regexp.MustCompile("^[a-z][a-z0-9_]*$")
+
+// pattern1 is the pattern of LoginResponse.Digits.
+var pattern1 = regexp.MustCompile("^[0-9]+$")
+
+// FieldDescriptor describes a field of a packet or struct.
+type FieldDescriptor struct {
+	Name string
+	// Type is the declared Go type, e.g. uint32, []Point or [4]byte.
+	Type string
+	// Wire is the wire kind: an integer type, string, struct, slice or array.
+	Wire string
+	// Elem is the wire kind of the elements of a slice or array.
+	Elem string
+	// Struct is the struct name of a struct field or of struct elements.
+	Struct   string
+	ArrayLen int
+	// Offset is the offset from the start of the packet, including the header, or of the struct.
+	// It is -1 once a field before has variable size.
+	Offset int
+	// Size is the wire size, or -1 if it depends on the value.
+	Size int
+}
+
+// PacketDescriptor describes a packet or a struct, ID and IDName are only set for packets.
+type PacketDescriptor struct {
+	Name   string
+	IDName string
+	ID     uint32
+	// Kind is SimplePacket, VLFPacket, Packet or Struct.
+	Kind   string
+	Fields []FieldDescriptor
+}
+
+func (d *PacketDescriptor) IsPacket() bool { return d.Kind != "Struct" }
+
+var descriptors = []PacketDescriptor{
+	{
+		Name: "Point",
+		Kind: "Struct",
+		Fields: []FieldDescriptor{
+			{Name: "X", Type: "int32", Wire: "int32", Offset: 0, Size: 4},
+			{Name: "Y", Type: "int16", Wire: "int16", Offset: 4, Size: 2},
+		},
+	},
+	{
+		Name: "Wrap",
+		Kind: "Struct",
+		Fields: []FieldDescriptor{
+			{Name: "P", Type: "Point", Wire: "struct", Struct: "Point", Offset: 0, Size: 6},
+			{Name: "Tag", Type: "string", Wire: "string", Offset: 6, Size: -1},
+		},
+	},
+	{
+		Name:   "Keepalive",
+		IDName: "KEEPALIVE",
+		ID:     KEEPALIVE,
+		Kind:   "SimplePacket",
+	},
+	{
+		Name:   "LoginRequest",
+		IDName: "LOGIN_REQUEST",
+		ID:     LOGIN_REQUEST,
+		Kind:   "Packet",
+		Fields: []FieldDescriptor{
+			{Name: "UserName", Type: "string", Wire: "string", Offset: 24, Size: -1},
+			{Name: "Code", Type: "string", Wire: "string", Offset: -1, Size: -1},
+			{Name: "Age", Type: "uint32", Wire: "uint32", Offset: -1, Size: 4},
+			{Name: "B", Type: "byte", Wire: "byte", Offset: -1, Size: 1},
+			{Name: "U8", Type: "uint8", Wire: "uint8", Offset: -1, Size: 1},
+			{Name: "U16", Type: "uint16", Wire: "uint16", Offset: -1, Size: 2},
+			{Name: "U64", Type: "uint64", Wire: "uint64", Offset: -1, Size: 8},
+			{Name: "I8", Type: "int8", Wire: "int8", Offset: -1, Size: 1},
+			{Name: "I16", Type: "int16", Wire: "int16", Offset: -1, Size: 2},
+			{Name: "I32", Type: "int32", Wire: "int32", Offset: -1, Size: 4},
+			{Name: "I64", Type: "int64", Wire: "int64", Offset: -1, Size: 8},
+			{Name: "Home", Type: "Point", Wire: "struct", Struct: "Point", Offset: -1, Size: 6},
+			{Name: "W", Type: "Wrap", Wire: "struct", Struct: "Wrap", Offset: -1, Size: -1},
+			{Name: "Pos", Type: "[2]Point", Wire: "array", Elem: "struct", Struct: "Point", ArrayLen: 2, Offset: -1, Size: 12},
+			{Name: "Fix", Type: "[3]byte", Wire: "array", Elem: "byte", ArrayLen: 3, Offset: -1, Size: 3},
+			{Name: "Arr", Type: "[2]int64", Wire: "array", Elem: "int64", ArrayLen: 2, Offset: -1, Size: 16},
+			{Name: "Names", Type: "[2]string", Wire: "array", Elem: "string", ArrayLen: 2, Offset: -1, Size: -1},
+			{Name: "Raw", Type: "[]byte", Wire: "slice", Elem: "byte", Offset: -1, Size: -1},
+			{Name: "Nums", Type: "[]uint16", Wire: "slice", Elem: "uint16", Offset: -1, Size: -1},
+			{Name: "Labels", Type: "[]string", Wire: "slice", Elem: "string", Offset: -1, Size: -1},
+			{Name: "Track", Type: "[]Point", Wire: "slice", Elem: "struct", Struct: "Point", Offset: -1, Size: -1},
+		},
+	},
+	{
+		Name:   "LoginResponse",
+		IDName: "LOGIN_RESPONSE",
+		ID:     LOGIN_RESPONSE,
+		Kind:   "Packet",
+		Fields: []FieldDescriptor{
+			{Name: "Result", Type: "int32", Wire: "int32", Offset: 24, Size: 4},
+			{Name: "Session", Type: "uint64", Wire: "uint64", Offset: 28, Size: 8},
+			{Name: "Digits", Type: "string", Wire: "string", Offset: 36, Size: -1},
+		},
+	},
+	{
+		Name:   "BuddyList",
+		IDName: "BUDDY_LIST",
+		ID:     BUDDY_LIST,
+		Kind:   "VLFPacket",
+		Fields: []FieldDescriptor{
+			{Name: "Buddies", Type: "[]Wrap", Wire: "slice", Elem: "struct", Struct: "Wrap", Offset: 24, Size: -1},
+		},
+	},
+}
+
+// Descriptors returns the descriptors of all packets and structs in the order of the protocol
+// definition, they are shared and must not be modified.
+func Descriptors() []PacketDescriptor { return descriptors }
+
+// LookupDescriptor returns the descriptor of a packet type, or nil if the type is unknown.
+func LookupDescriptor(packetType uint32) *PacketDescriptor {
+	for i := range descriptors {
+		if d := &descriptors[i]; d.IsPacket() && d.ID == packetType {
+			return d
+		}
+	}
+	return nil
+}
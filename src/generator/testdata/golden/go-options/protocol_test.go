@@ -0,0 +1,297 @@
+package protocol
+
+import (
+	"bytes"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// randomLen returns a random length from lo to hi.
+func randomLen(r *rand.Rand, lo, hi int) int { return lo + r.Intn(hi-lo+1) }
+
+func randomBytes(r *rand.Rand, lo, hi int) []byte {
+	b := make([]byte, randomLen(r, lo, hi))
+	r.Read(b)
+	return b
+}
+
+func randomString(r *rand.Rand, lo, hi int) string { return string(randomBytes(r, lo, hi)) }
+
+// randomUint returns a random value from lo to hi.
+func randomUint(r *rand.Rand, lo, hi uint64) uint64 {
+	if n := hi - lo + 1; n != 0 {
+		return lo + r.Uint64()%n
+	}
+	return r.Uint64()
+}
+
+// randomInt returns a random value from lo to hi.
+func randomInt(r *rand.Rand, lo, hi int64) int64 { return int64(randomUint(r, uint64(lo), uint64(hi))) }
+
+// randomChoice returns one of the values matching the pattern of a string field.
+func randomChoice(r *rand.Rand, values ...string) string { return values[r.Intn(len(values))] }
+
+// headerSize is the wire size of the packet header, headerPadding the number of reserved
+// bytes after the fields which Length() counts in.
+const headerSize, headerPadding = 24, 12
+
+// encodePacket returns the encoding of p followed by the reserved bytes, the header is written as it is.
+func encodePacket(t testing.TB, p Packet) []byte {
+	t.Helper()
+	buff := make([]byte, p.Length())
+	w := NewBigEndianStream(buff)
+	if err := p.Write(w); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if w.Left() != headerPadding {
+		t.Fatalf("Length() is %d, but %d bytes were written besides the %d reserved ones", p.Length(), p.Length()-w.Left(), headerPadding)
+	}
+	return buff
+}
+
+func (s *Point) randomize(r *rand.Rand) {
+	s.X = int32(randomInt(r, -100, 100))
+	s.Y = int16(r.Uint64())
+}
+
+func (s *Wrap) randomize(r *rand.Rand) {
+	s.P.randomize(r)
+	s.Tag = randomString(r, 0, 8)
+}
+
+func (s *Keepalive) randomize(r *rand.Rand) {
+}
+
+func (s *LoginRequest) randomize(r *rand.Rand) {
+	s.UserName = randomChoice(r, "x0k7", "gz", "i", "m_ss")
+	s.Code = randomString(r, 4, 4)
+	s.Age = uint32(randomUint(r, 1, 200))
+	s.B = byte(r.Uint64())
+	s.U8 = uint8(r.Uint64())
+	s.U16 = uint16(r.Uint64())
+	s.U64 = uint64(r.Uint64())
+	s.I8 = int8(r.Uint64())
+	s.I16 = int16(r.Uint64())
+	s.I32 = int32(r.Uint64())
+	s.I64 = int64(r.Uint64())
+	s.Home.randomize(r)
+	s.W.randomize(r)
+	for i := range s.Pos {
+		s.Pos[i].randomize(r)
+	}
+	for i := range s.Fix {
+		s.Fix[i] = byte(r.Uint64())
+	}
+	for i := range s.Arr {
+		s.Arr[i] = int64(r.Uint64())
+	}
+	for i := range s.Names {
+		s.Names[i] = randomString(r, 0, 15)
+	}
+	s.Raw = randomBytes(r, 0, 15)
+	s.Nums = make([]uint16, randomLen(r, 1, 4))
+	for i := range s.Nums {
+		s.Nums[i] = uint16(r.Uint64())
+	}
+	s.Labels = make([]string, randomLen(r, 0, 3))
+	for i := range s.Labels {
+		s.Labels[i] = randomString(r, 0, 15)
+	}
+	s.Track = make([]Point, randomLen(r, 0, 3))
+	for i := range s.Track {
+		s.Track[i].randomize(r)
+	}
+}
+
+func (s *LoginResponse) randomize(r *rand.Rand) {
+	s.Result = int32(r.Uint64())
+	s.Session = uint64(r.Uint64())
+	// no value matching the pattern within the length bounds was found, Digits keeps its initial value
+}
+
+func (s *BuddyList) randomize(r *rand.Rand) {
+	s.Buddies = make([]Wrap, randomLen(r, 0, 3))
+	for i := range s.Buddies {
+		s.Buddies[i].randomize(r)
+	}
+}
+
+// roundTrips is the number of random values each packet is tested with.
+const roundTrips = 100
+
+// testRoundTrip writes p, reads it back by the packet factory and compares the result.
+func testRoundTrip(t *testing.T, p Packet) {
+	t.Helper()
+	if err := p.(Validator).Validate(); err != nil {
+		t.Fatalf("random packet is invalid: %v", err)
+	}
+	p.AdjustLength()
+	r := NewBigEndianStream(encodePacket(t, p))
+	q, err := NewPacketFactory(nil).CreatePacket(r)
+	if err != nil {
+		t.Fatalf("CreatePacket: %v", err)
+	}
+	if r.Left() != headerPadding {
+		t.Fatalf("%d bytes were left after reading, only the %d reserved ones should be", r.Left(), headerPadding)
+	}
+	if !reflect.DeepEqual(p, q) {
+		t.Fatalf("read packet differs from the written one\nwritten: %#v\nread:    %#v", p, q)
+	}
+}
+
+func TestKeepaliveRoundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(0x00000001))
+	for i := 0; i < roundTrips; i++ {
+		p := NewKeepalive()
+		p.ID, p.Version, p.Ack, p.Token = r.Uint32(), r.Uint32(), r.Uint32(), r.Uint32()
+		p.randomize(r)
+		testRoundTrip(t, p)
+	}
+}
+
+func TestLoginRequestRoundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(0x00000002))
+	for i := 0; i < roundTrips; i++ {
+		p := NewLoginRequest()
+		p.ID, p.Version, p.Ack, p.Token = r.Uint32(), r.Uint32(), r.Uint32(), r.Uint32()
+		p.randomize(r)
+		testRoundTrip(t, p)
+	}
+}
+
+func TestLoginResponseRoundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(0x80000002))
+	for i := 0; i < roundTrips; i++ {
+		p := NewLoginResponse()
+		p.ID, p.Version, p.Ack, p.Token = r.Uint32(), r.Uint32(), r.Uint32(), r.Uint32()
+		p.randomize(r)
+		testRoundTrip(t, p)
+	}
+}
+
+func TestBuddyListRoundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(0x80000003))
+	for i := 0; i < roundTrips; i++ {
+		p := NewBuddyList()
+		p.ID, p.Version, p.Ack, p.Token = r.Uint32(), r.Uint32(), r.Uint32(), r.Uint32()
+		p.randomize(r)
+		testRoundTrip(t, p)
+	}
+}
+
+// fuzzSeeds is the number of random values each packet adds to the seed corpus, besides its zero value.
+const fuzzSeeds = 3
+
+// checkReencode writes p, which was read from data, and compares the result with the bytes consumed.
+// skip is the length of the header which is written but was not read.
+func checkReencode(t *testing.T, p Packet, data []byte, consumed, skip int) {
+	t.Helper()
+	if n := p.Length() - headerPadding - skip; n != consumed {
+		t.Fatalf("%d bytes were read, but Length() gives %d", consumed, n)
+	}
+	if b := encodePacket(t, p)[skip : skip+consumed]; !bytes.Equal(b, data[:consumed]) {
+		t.Fatalf("re-encoded packet differs from the input\ninput:      %x\nre-encoded: %x", data[:consumed], b)
+	}
+}
+
+func FuzzCreatePacket(f *testing.F) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i <= fuzzSeeds; i++ {
+		p := NewKeepalive()
+		if i != 0 {
+			p.randomize(r)
+		}
+		p.AdjustLength()
+		f.Add(encodePacket(f, p))
+	}
+	for i := 0; i <= fuzzSeeds; i++ {
+		p := NewLoginRequest()
+		if i != 0 {
+			p.randomize(r)
+		}
+		p.AdjustLength()
+		f.Add(encodePacket(f, p))
+	}
+	for i := 0; i <= fuzzSeeds; i++ {
+		p := NewLoginResponse()
+		if i != 0 {
+			p.randomize(r)
+		}
+		p.AdjustLength()
+		f.Add(encodePacket(f, p))
+	}
+	for i := 0; i <= fuzzSeeds; i++ {
+		p := NewBuddyList()
+		if i != 0 {
+			p.randomize(r)
+		}
+		p.AdjustLength()
+		f.Add(encodePacket(f, p))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		s := NewBigEndianStream(data)
+		p, err := NewPacketFactory(nil).CreatePacket(s)
+		if err != nil {
+			return
+		}
+		checkReencode(t, p, data, len(data)-s.Left(), 0)
+	})
+}
+
+func FuzzLoginRequestRead(f *testing.F) {
+	r := rand.New(rand.NewSource(0x00000002))
+	for i := 0; i <= fuzzSeeds; i++ {
+		p := NewLoginRequest()
+		if i != 0 {
+			p.randomize(r)
+		}
+		f.Add(encodePacket(f, p)[headerSize:])
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		s := NewBigEndianStream(data)
+		p := NewLoginRequest()
+		if err := p.Read(s); err != nil {
+			return
+		}
+		checkReencode(t, p, data, len(data)-s.Left(), headerSize)
+	})
+}
+
+func FuzzLoginResponseRead(f *testing.F) {
+	r := rand.New(rand.NewSource(0x80000002))
+	for i := 0; i <= fuzzSeeds; i++ {
+		p := NewLoginResponse()
+		if i != 0 {
+			p.randomize(r)
+		}
+		f.Add(encodePacket(f, p)[headerSize:])
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		s := NewBigEndianStream(data)
+		p := NewLoginResponse()
+		if err := p.Read(s); err != nil {
+			return
+		}
+		checkReencode(t, p, data, len(data)-s.Left(), headerSize)
+	})
+}
+
+func FuzzBuddyListRead(f *testing.F) {
+	r := rand.New(rand.NewSource(0x80000003))
+	for i := 0; i <= fuzzSeeds; i++ {
+		p := NewBuddyList()
+		if i != 0 {
+			p.randomize(r)
+		}
+		f.Add(encodePacket(f, p)[headerSize:])
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		s := NewBigEndianStream(data)
+		p := NewBuddyList()
+		if err := p.Read(s); err != nil {
+			return
+		}
+		checkReencode(t, p, data, len(data)-s.Left(), headerSize)
+	})
+}
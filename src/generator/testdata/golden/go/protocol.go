@@ -0,0 +1,888 @@
+package protocol
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrUnknownPacket = errors.New("unknown packet")
+
+const (
+	KEEPALIVE      = 0x00000001
+	LOGIN_REQUEST  = 0x00000002
+	LOGIN_RESPONSE = 0x80000002
+	BUDDY_LIST     = 0x80000003
+)
+
+type Packet interface {
+	GetID() uint32
+	SetID(uint32)
+	GetToken() uint32
+	SetToken(uint32)
+	GetAck() uint32
+	SetAck(uint32)
+	GetPacketType() uint32
+	Length() int
+	AdjustLength()
+	Read(stream ReadStream) error
+	Write(stream WriteStream) error
+}
+
+// Resetter is implemented by the generated packets and structs, Reset restores the value
+// New<Name>() returns. It is kept out of Packet for the implementations written by hand.
+type Resetter interface {
+	Reset()
+}
+
+type PacketHeader struct {
+	ID         uint32
+	PacketType uint32
+	Len        uint32
+	Version    uint32
+	Ack        uint32
+	Token      uint32
+}
+
+func (p *PacketHeader) GetID() uint32 { return p.ID }
+
+func (p *PacketHeader) SetID(id uint32) { p.ID = id }
+
+func (p *PacketHeader) GetToken() uint32 { return p.Token }
+
+func (p *PacketHeader) SetToken(token uint32) { p.Token = token }
+
+func (p *PacketHeader) GetAck() uint32 { return p.Ack }
+
+func (p *PacketHeader) SetAck(ack uint32) { p.Ack = ack }
+
+func (p *PacketHeader) GetPacketType() uint32 { return p.PacketType }
+
+// Length is what the header counts for in Len: the 24 bytes it writes and the
+// 12 reserved bytes after the fields of the packet.
+func (p *PacketHeader) Length() int { return 36 }
+
+func (p *PacketHeader) AdjustLength() { p.Len = uint32(p.Length()) }
+
+func (p *PacketHeader) Read(stream ReadStream) error {
+	var err error
+	if p.ID, err = stream.ReadUint32(); err != nil {
+		return err
+	}
+	if p.PacketType, err = stream.ReadUint32(); err != nil {
+		return err
+	}
+	if p.Len, err = stream.ReadUint32(); err != nil {
+		return err
+	}
+	if p.Version, err = stream.ReadUint32(); err != nil {
+		return err
+	}
+	if p.Ack, err = stream.ReadUint32(); err != nil {
+		return err
+	}
+	if p.Token, err = stream.ReadUint32(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (w *PacketHeader) Write(stream WriteStream) error {
+	var err error
+	if err = stream.WriteUint32(w.ID); err != nil {
+		return err
+	}
+	if err = stream.WriteUint32(w.PacketType); err != nil {
+		return err
+	}
+	if err = stream.WriteUint32(w.Len); err != nil {
+		return err
+	}
+	if err = stream.WriteUint32(w.Version); err != nil {
+		return err
+	}
+	if err = stream.WriteUint32(w.Ack); err != nil {
+		return err
+	}
+	if err = stream.WriteUint32(w.Token); err != nil {
+		return err
+	}
+	return nil
+}
+
+type Point struct {
+	X int32
+	Y int16
+}
+
+// Reset sets the fields to their defaults.
+func (s *Point) Reset() {
+	*s = Point{
+		X: -1,
+	}
+}
+
+func (s *Point) Length() int {
+	var totalLength int
+	totalLength += 4
+	totalLength += 2
+	return totalLength
+}
+
+func (s *Point) AdjustLength() {}
+
+func (s *Point) Read(stream ReadStream) error {
+	var err error
+	if val, err := stream.ReadUint32(); err != nil {
+		return err
+	} else {
+		s.X = int32(val)
+	}
+	if val, err := stream.ReadUint16(); err != nil {
+		return err
+	} else {
+		s.Y = int16(val)
+	}
+	return err
+}
+
+func (s *Point) Write(stream WriteStream) error {
+	var err error
+	if err = stream.WriteUint32(uint32(s.X)); err != nil {
+		return err
+	}
+	if err = stream.WriteUint16(uint16(s.Y)); err != nil {
+		return err
+	}
+	return err
+}
+
+type Wrap struct {
+	P   Point
+	Tag string
+}
+
+// Reset sets the fields to their defaults.
+func (s *Wrap) Reset() {
+	*s = Wrap{
+		P: Point{X: -1},
+	}
+}
+
+func (s *Wrap) Length() int {
+	var totalLength int
+	totalLength += s.P.Length()
+	totalLength += 4 + len(s.Tag)
+	return totalLength
+}
+
+func (s *Wrap) AdjustLength() {}
+
+func (s *Wrap) Read(stream ReadStream) error {
+	var err error
+	if err = s.P.Read(stream); err != nil {
+		return err
+	}
+	{
+		var size uint32
+		if size, err = stream.ReadUint32(); err != nil {
+			return err
+		}
+		if err = checkBytes("Wrap.Tag", size, 8); err != nil {
+			return err
+		}
+		var buff []byte
+		if buff, err = stream.ReadBuff(int(size)); err != nil {
+			return err
+		}
+		s.Tag = string(buff)
+	}
+	return err
+}
+
+func (s *Wrap) Write(stream WriteStream) error {
+	var err error
+	if err = s.P.Write(stream); err != nil {
+		return err
+	}
+	if err = stream.WriteUint32(uint32(len(s.Tag))); err != nil {
+		return err
+	}
+	if err = stream.WriteBuff([]byte(s.Tag)); err != nil {
+		return err
+	}
+	return err
+}
+
+type Keepalive struct {
+	PacketHeader
+}
+
+func NewKeepalive() *Keepalive {
+	return &Keepalive{
+		PacketHeader: PacketHeader{
+			PacketType: KEEPALIVE,
+		},
+	}
+}
+
+// Reset sets the fields to their defaults and clears the header except the PacketType.
+func (s *Keepalive) Reset() {
+	*s = Keepalive{
+		PacketHeader: PacketHeader{
+			PacketType: KEEPALIVE,
+		},
+	}
+}
+
+func (s *Keepalive) Length() int {
+	var totalLength int
+	totalLength += s.PacketHeader.Length()
+	return totalLength
+}
+
+func (s *Keepalive) AdjustLength() { s.PacketHeader.Len = uint32(s.Length()) }
+
+func (s *Keepalive) Read(stream ReadStream) error { return nil }
+
+func (s *Keepalive) Write(stream WriteStream) error {
+	var err error
+	if err = s.PacketHeader.Write(stream); err != nil {
+		return err
+	}
+	return err
+}
+
+type LoginRequest struct {
+	PacketHeader
+	UserName string
+	Code     string
+	Age      uint32
+	B        byte
+	U8       uint8
+	U16      uint16
+	U64      uint64
+	I8       int8
+	I16      int16
+	I32      int32
+	I64      int64
+	Home     Point
+	W        Wrap
+	Pos      [2]Point
+	Fix      [3]byte
+	Arr      [2]int64
+	Names    [2]string
+	Raw      []byte
+	Nums     []uint16
+	Labels   []string
+	Track    []Point
+}
+
+func NewLoginRequest() *LoginRequest {
+	return &LoginRequest{
+		PacketHeader: PacketHeader{
+			PacketType: LOGIN_REQUEST,
+		},
+		UserName: "guest",
+		Age:      18,
+		Home:     Point{X: -1},
+		W:        Wrap{P: Point{X: -1}},
+		Pos:      [2]Point{{X: -1}, {X: -1}},
+	}
+}
+
+// Reset sets the fields to their defaults and clears the header except the PacketType.
+func (s *LoginRequest) Reset() {
+	*s = LoginRequest{
+		PacketHeader: PacketHeader{
+			PacketType: LOGIN_REQUEST,
+		},
+		UserName: "guest",
+		Age:      18,
+		Home:     Point{X: -1},
+		W:        Wrap{P: Point{X: -1}},
+		Pos:      [2]Point{{X: -1}, {X: -1}},
+	}
+}
+
+func (s *LoginRequest) Length() int {
+	var totalLength int
+	totalLength += s.PacketHeader.Length()
+	totalLength += 4 + len(s.UserName)
+	totalLength += 4 + len(s.Code)
+	totalLength += 4
+	totalLength += 1
+	totalLength += 1
+	totalLength += 2
+	totalLength += 8
+	totalLength += 1
+	totalLength += 2
+	totalLength += 4
+	totalLength += 8
+	totalLength += s.Home.Length()
+	totalLength += s.W.Length()
+	for i := range s.Pos {
+		totalLength += s.Pos[i].Length()
+	}
+	totalLength += len(s.Fix) * 1
+	totalLength += len(s.Arr) * 8
+	for i := range s.Names {
+		totalLength += 4 + len(s.Names[i])
+	}
+	totalLength += 4
+	totalLength += len(s.Raw) * 1
+	totalLength += 4
+	totalLength += len(s.Nums) * 2
+	totalLength += 4
+	for i := range s.Labels {
+		totalLength += 4 + len(s.Labels[i])
+	}
+	totalLength += 4
+	for i := range s.Track {
+		totalLength += s.Track[i].Length()
+	}
+	return totalLength
+}
+
+func (s *LoginRequest) AdjustLength() { s.PacketHeader.Len = uint32(s.Length()) }
+
+func (s *LoginRequest) Read(stream ReadStream) error {
+	var err error
+	{
+		var size uint32
+		if size, err = stream.ReadUint32(); err != nil {
+			return err
+		}
+		if err = checkBytes("LoginRequest.UserName", size, 16); err != nil {
+			return err
+		}
+		var buff []byte
+		if buff, err = stream.ReadBuff(int(size)); err != nil {
+			return err
+		}
+		s.UserName = string(buff)
+	}
+	{
+		var size uint32
+		if size, err = stream.ReadUint32(); err != nil {
+			return err
+		}
+		if err = checkBytes("LoginRequest.Code", size, -1); err != nil {
+			return err
+		}
+		var buff []byte
+		if buff, err = stream.ReadBuff(int(size)); err != nil {
+			return err
+		}
+		s.Code = string(buff)
+	}
+	if s.Age, err = stream.ReadUint32(); err != nil {
+		return err
+	}
+	if s.B, err = stream.ReadByte(); err != nil {
+		return err
+	}
+	if s.U8, err = stream.ReadByte(); err != nil {
+		return err
+	}
+	if s.U16, err = stream.ReadUint16(); err != nil {
+		return err
+	}
+	if s.U64, err = stream.ReadUint64(); err != nil {
+		return err
+	}
+	if val, err := stream.ReadByte(); err != nil {
+		return err
+	} else {
+		s.I8 = int8(val)
+	}
+	if val, err := stream.ReadUint16(); err != nil {
+		return err
+	} else {
+		s.I16 = int16(val)
+	}
+	if val, err := stream.ReadUint32(); err != nil {
+		return err
+	} else {
+		s.I32 = int32(val)
+	}
+	if val, err := stream.ReadUint64(); err != nil {
+		return err
+	} else {
+		s.I64 = int64(val)
+	}
+	if err = s.Home.Read(stream); err != nil {
+		return err
+	}
+	if err = s.W.Read(stream); err != nil {
+		return err
+	}
+	for i := range s.Pos {
+		if err = s.Pos[i].Read(stream); err != nil {
+			return err
+		}
+	}
+	for i := range s.Fix {
+		if s.Fix[i], err = stream.ReadByte(); err != nil {
+			return err
+		}
+	}
+	for i := range s.Arr {
+		if val, err := stream.ReadUint64(); err != nil {
+			return err
+		} else {
+			s.Arr[i] = int64(val)
+		}
+	}
+	for i := range s.Names {
+		{
+			var size uint32
+			if size, err = stream.ReadUint32(); err != nil {
+				return err
+			}
+			if err = checkBytes("LoginRequest.Names", size, -1); err != nil {
+				return err
+			}
+			var buff []byte
+			if buff, err = stream.ReadBuff(int(size)); err != nil {
+				return err
+			}
+			s.Names[i] = string(buff)
+		}
+	}
+	{
+		var size uint32
+		if size, err = stream.ReadUint32(); err != nil {
+			return err
+		}
+		if err = checkBytes("LoginRequest.Raw", size, 32); err != nil {
+			return err
+		}
+		if s.Raw, err = stream.ReadBuff(int(size)); err != nil {
+			return err
+		}
+	}
+	{
+		var size uint32
+		if size, err = stream.ReadUint32(); err != nil {
+			return err
+		}
+		if err = checkCount("LoginRequest.Nums", size, 4, 2, stream); err != nil {
+			return err
+		}
+		s.Nums = make([]uint16, size)
+		for i := range s.Nums {
+			if s.Nums[i], err = stream.ReadUint16(); err != nil {
+				return err
+			}
+		}
+	}
+	{
+		var size uint32
+		if size, err = stream.ReadUint32(); err != nil {
+			return err
+		}
+		if err = checkCount("LoginRequest.Labels", size, -1, 4, stream); err != nil {
+			return err
+		}
+		s.Labels = make([]string, size)
+		for i := range s.Labels {
+			{
+				var size uint32
+				if size, err = stream.ReadUint32(); err != nil {
+					return err
+				}
+				if err = checkBytes("LoginRequest.Labels", size, -1); err != nil {
+					return err
+				}
+				var buff []byte
+				if buff, err = stream.ReadBuff(int(size)); err != nil {
+					return err
+				}
+				s.Labels[i] = string(buff)
+			}
+		}
+	}
+	{
+		var size uint32
+		if size, err = stream.ReadUint32(); err != nil {
+			return err
+		}
+		if err = checkCount("LoginRequest.Track", size, 8, 6, stream); err != nil {
+			return err
+		}
+		s.Track = make([]Point, size)
+		for i := range s.Track {
+			if err = s.Track[i].Read(stream); err != nil {
+				return err
+			}
+		}
+	}
+	return err
+}
+
+func (s *LoginRequest) Write(stream WriteStream) error {
+	var err error
+	if err = s.PacketHeader.Write(stream); err != nil {
+		return err
+	}
+	if err = stream.WriteUint32(uint32(len(s.UserName))); err != nil {
+		return err
+	}
+	if err = stream.WriteBuff([]byte(s.UserName)); err != nil {
+		return err
+	}
+	if err = stream.WriteUint32(uint32(len(s.Code))); err != nil {
+		return err
+	}
+	if err = stream.WriteBuff([]byte(s.Code)); err != nil {
+		return err
+	}
+	if err = stream.WriteUint32(s.Age); err != nil {
+		return err
+	}
+	if err = stream.WriteByte(s.B); err != nil {
+		return err
+	}
+	if err = stream.WriteByte(s.U8); err != nil {
+		return err
+	}
+	if err = stream.WriteUint16(s.U16); err != nil {
+		return err
+	}
+	if err = stream.WriteUint64(s.U64); err != nil {
+		return err
+	}
+	if err = stream.WriteByte(byte(s.I8)); err != nil {
+		return err
+	}
+	if err = stream.WriteUint16(uint16(s.I16)); err != nil {
+		return err
+	}
+	if err = stream.WriteUint32(uint32(s.I32)); err != nil {
+		return err
+	}
+	if err = stream.WriteUint64(uint64(s.I64)); err != nil {
+		return err
+	}
+	if err = s.Home.Write(stream); err != nil {
+		return err
+	}
+	if err = s.W.Write(stream); err != nil {
+		return err
+	}
+	for i := range s.Pos {
+		if err = s.Pos[i].Write(stream); err != nil {
+			return err
+		}
+	}
+	for i := range s.Fix {
+		if err = stream.WriteByte(s.Fix[i]); err != nil {
+			return err
+		}
+	}
+	for i := range s.Arr {
+		if err = stream.WriteUint64(uint64(s.Arr[i])); err != nil {
+			return err
+		}
+	}
+	for i := range s.Names {
+		if err = stream.WriteUint32(uint32(len(s.Names[i]))); err != nil {
+			return err
+		}
+		if err = stream.WriteBuff([]byte(s.Names[i])); err != nil {
+			return err
+		}
+	}
+	if err = stream.WriteUint32(uint32(len(s.Raw))); err != nil {
+		return err
+	}
+	if err = stream.WriteBuff(s.Raw); err != nil {
+		return err
+	}
+	if err = stream.WriteUint32(uint32(len(s.Nums))); err != nil {
+		return err
+	}
+	for i := range s.Nums {
+		if err = stream.WriteUint16(s.Nums[i]); err != nil {
+			return err
+		}
+	}
+	if err = stream.WriteUint32(uint32(len(s.Labels))); err != nil {
+		return err
+	}
+	for i := range s.Labels {
+		if err = stream.WriteUint32(uint32(len(s.Labels[i]))); err != nil {
+			return err
+		}
+		if err = stream.WriteBuff([]byte(s.Labels[i])); err != nil {
+			return err
+		}
+	}
+	if err = stream.WriteUint32(uint32(len(s.Track))); err != nil {
+		return err
+	}
+	for i := range s.Track {
+		if err = s.Track[i].Write(stream); err != nil {
+			return err
+		}
+	}
+	return err
+}
+
+type LoginResponse struct {
+	PacketHeader
+	Result  int32
+	Session uint64
+	Digits  string
+}
+
+func NewLoginResponse() *LoginResponse {
+	return &LoginResponse{
+		PacketHeader: PacketHeader{
+			PacketType: LOGIN_RESPONSE,
+		},
+		Digits: "000000000000",
+	}
+}
+
+// Reset sets the fields to their defaults and clears the header except the PacketType.
+func (s *LoginResponse) Reset() {
+	*s = LoginResponse{
+		PacketHeader: PacketHeader{
+			PacketType: LOGIN_RESPONSE,
+		},
+		Digits: "000000000000",
+	}
+}
+
+func (s *LoginResponse) Length() int {
+	var totalLength int
+	totalLength += s.PacketHeader.Length()
+	totalLength += 4
+	totalLength += 8
+	totalLength += 4 + len(s.Digits)
+	return totalLength
+}
+
+func (s *LoginResponse) AdjustLength() { s.PacketHeader.Len = uint32(s.Length()) }
+
+func (s *LoginResponse) Read(stream ReadStream) error {
+	var err error
+	if val, err := stream.ReadUint32(); err != nil {
+		return err
+	} else {
+		s.Result = int32(val)
+	}
+	if s.Session, err = stream.ReadUint64(); err != nil {
+		return err
+	}
+	{
+		var size uint32
+		if size, err = stream.ReadUint32(); err != nil {
+			return err
+		}
+		if err = checkBytes("LoginResponse.Digits", size, -1); err != nil {
+			return err
+		}
+		var buff []byte
+		if buff, err = stream.ReadBuff(int(size)); err != nil {
+			return err
+		}
+		s.Digits = string(buff)
+	}
+	return err
+}
+
+func (s *LoginResponse) Write(stream WriteStream) error {
+	var err error
+	if err = s.PacketHeader.Write(stream); err != nil {
+		return err
+	}
+	if err = stream.WriteUint32(uint32(s.Result)); err != nil {
+		return err
+	}
+	if err = stream.WriteUint64(s.Session); err != nil {
+		return err
+	}
+	if err = stream.WriteUint32(uint32(len(s.Digits))); err != nil {
+		return err
+	}
+	if err = stream.WriteBuff([]byte(s.Digits)); err != nil {
+		return err
+	}
+	return err
+}
+
+type BuddyList struct {
+	PacketHeader
+	Buddies []Wrap
+}
+
+func NewBuddyList() *BuddyList {
+	return &BuddyList{
+		PacketHeader: PacketHeader{
+			PacketType: BUDDY_LIST,
+		},
+	}
+}
+
+// Reset sets the fields to their defaults and clears the header except the PacketType.
+func (s *BuddyList) Reset() {
+	*s = BuddyList{
+		PacketHeader: PacketHeader{
+			PacketType: BUDDY_LIST,
+		},
+	}
+}
+
+func (s *BuddyList) Length() int {
+	var totalLength int
+	totalLength += s.PacketHeader.Length()
+	totalLength += 4
+	for i := range s.Buddies {
+		totalLength += s.Buddies[i].Length()
+	}
+	return totalLength
+}
+
+func (s *BuddyList) AdjustLength() { s.PacketHeader.Len = uint32(s.Length()) }
+
+func (s *BuddyList) Read(stream ReadStream) error {
+	var err error
+	{
+		var size uint32
+		if size, err = stream.ReadUint32(); err != nil {
+			return err
+		}
+		if err = checkCount("BuddyList.Buddies", size, -1, 10, stream); err != nil {
+			return err
+		}
+		s.Buddies = make([]Wrap, size)
+		for i := range s.Buddies {
+			if err = s.Buddies[i].Read(stream); err != nil {
+				return err
+			}
+		}
+	}
+	return err
+}
+
+func (s *BuddyList) Write(stream WriteStream) error {
+	var err error
+	if err = s.PacketHeader.Write(stream); err != nil {
+		return err
+	}
+	if err = stream.WriteUint32(uint32(len(s.Buddies))); err != nil {
+		return err
+	}
+	for i := range s.Buddies {
+		if err = s.Buddies[i].Write(stream); err != nil {
+			return err
+		}
+	}
+	return err
+}
+
+type PacketCacher interface {
+	Get(id uint32, header *PacketHeader) Packet
+	Put(id uint32, packet Packet)
+}
+
+type PacketFactory struct {
+	Cacher PacketCacher
+}
+
+func NewPacketFactory(cacher PacketCacher) *PacketFactory {
+	return &PacketFactory{
+		Cacher: cacher,
+	}
+}
+
+func (p *PacketFactory) CreatePacket(stream ReadStream) (newPacket Packet, err error) {
+	var header PacketHeader
+	if err = header.Read(stream); err != nil {
+		return nil, err
+	}
+	if Limits.MaxTotalBytes > 0 && uint64(header.Len) > uint64(Limits.MaxTotalBytes) {
+		return nil, &LimitError{Field: "PacketHeader.Len", Length: header.Len, Limit: "MaxTotalBytes", Max: Limits.MaxTotalBytes}
+	}
+	if p.Cacher != nil {
+		newPacket = p.Cacher.Get(header.PacketType, &header)
+	}
+	if newPacket == nil {
+		switch header.PacketType {
+		case KEEPALIVE:
+			newPacket = &Keepalive{PacketHeader: header}
+		case LOGIN_REQUEST:
+			newPacket = &LoginRequest{PacketHeader: header}
+		case LOGIN_RESPONSE:
+			newPacket = &LoginResponse{PacketHeader: header}
+		case BUDDY_LIST:
+			newPacket = &BuddyList{PacketHeader: header}
+		default:
+			return nil, ErrUnknownPacket
+		}
+	}
+	if err = newPacket.Read(stream); err != nil {
+		return nil, err
+	}
+	return newPacket, nil
+}
+
+// DecodeLimits bounds the lengths Read accepts from the stream before it allocates, 0 disables a limit.
+type DecodeLimits struct {
+	// MaxElements limits the element count of slices other than byte slices.
+	MaxElements int
+	// MaxStringBytes limits the length of strings and byte slices.
+	MaxStringBytes int
+	// MaxTotalBytes limits the Len in the header of a packet created by a PacketFactory.
+	MaxTotalBytes int
+}
+
+// Limits are checked by the Read methods in addition to the max annotations of the fields.
+// Regardless of them, a slice is never allocated with more elements than the data left can hold.
+var Limits = DecodeLimits{MaxElements: 1 << 20, MaxStringBytes: 1 << 24, MaxTotalBytes: 1 << 26}
+
+// LimitError reports a length read from the stream which exceeds a limit.
+type LimitError struct {
+	// Field is the field name qualified by its packet or struct, e.g. LoginRequest.UserName.
+	Field  string
+	Length uint32
+	// Limit names the exceeded limit: max for the annotation of the field, a field of
+	// DecodeLimits, or Left if the data left can't hold the elements.
+	Limit string
+	Max   int
+}
+
+func (e *LimitError) Error() string {
+	if e.Limit == "Left" {
+		return fmt.Sprintf("%s: %d elements need more than the %d bytes left", e.Field, e.Length, e.Max)
+	}
+	return fmt.Sprintf("%s: length %d exceeds %s %d", e.Field, e.Length, e.Limit, e.Max)
+}
+
+// checkCount checks the element count of a slice before it is allocated. max is the annotation
+// of the field, -1 if there is none, and minSize is the least wire size of an element.
+func checkCount(field string, count uint32, max, minSize int, stream ReadStream) error {
+	switch {
+	case max >= 0 && uint64(count) > uint64(max):
+		return &LimitError{Field: field, Length: count, Limit: "max", Max: max}
+	case Limits.MaxElements > 0 && uint64(count) > uint64(Limits.MaxElements):
+		return &LimitError{Field: field, Length: count, Limit: "MaxElements", Max: Limits.MaxElements}
+	case uint64(count)*uint64(minSize) > uint64(stream.Left()):
+		return &LimitError{Field: field, Length: count, Limit: "Left", Max: stream.Left()}
+	}
+	return nil
+}
+
+// checkBytes checks the length of a string or byte slice before it is read,
+// max is the annotation of the field, -1 if there is none.
+func checkBytes(field string, length uint32, max int) error {
+	switch {
+	case max >= 0 && uint64(length) > uint64(max):
+		return &LimitError{Field: field, Length: length, Limit: "max", Max: max}
+	case Limits.MaxStringBytes > 0 && uint64(length) > uint64(Limits.MaxStringBytes):
+		return &LimitError{Field: field, Length: length, Limit: "MaxStringBytes", Max: Limits.MaxStringBytes}
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package protocol
+
+// Point is a position on the map.
+type Point struct {
+	X int32 `goproto:"min=-100,max=100,default=-1"`
+	Y int16
+}
+
+// Wrap nests a struct.
+type Wrap struct {
+	P   Point
+	Tag string `goproto:"max=8"`
+}
+
+// @SimplePacket: KEEPALIVE, 0x00000001
+type Keepalive struct{}
+
+// LoginRequest logs a user in.
+// @Packet: LOGIN_REQUEST, 0x00000002
+type LoginRequest struct {
+	// UserName is the account name.
+	UserName string `goproto:"required,max=16,pattern='^[a-z][a-z0-9_]*$',default=guest"`
+	Code     string `goproto:"len=4"`
+	Age      uint32 `goproto:"min=1,max=200,default=18"`
+	B        byte
+	U8       uint8
+	U16      uint16
+	U64      uint64
+	I8       int8
+	I16      int16
+	I32      int32
+	I64      int64
+	Home     Point
+	W        Wrap
+	Pos      [2]Point
+	Fix      [3]byte
+	Arr      [2]int64
+	Names    [2]string
+	Raw      []byte   `goproto:"max=32"`
+	Nums     []uint16 `goproto:"min=1,max=4"`
+	Labels   []string
+	Track    []Point `goproto:"max=8"`
+}
+
+// LoginResponse answers LoginRequest.
+// @Packet: LOGIN_RESPONSE, 0x80000002
+type LoginResponse struct {
+	Result  int32 // zero on success
+	Session uint64
+	// Digits has no random test value, the pattern repeats too few times for its length.
+	Digits string `goproto:"pattern='^[0-9]+$',len=12,default=000000000000"`
+}
+
+// @VLFPacket: BUDDY_LIST, 0x80000003
+type BuddyList struct {
+	Buddies []Wrap
+}
@@ -0,0 +1,125 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+func init() {
+	RegisterBackend("typescript", tsBackend{})
+}
+
+// tsBackend generates a TypeScript module with an interface per packet and struct,
+// DataView based read/write functions and a decoder returning a union of the packets
+// discriminated by packetType.
+type tsBackend struct{}
+
+func (tsBackend) Generate(schema *Schema, opts Options) (map[string][]byte, error) {
+	endian, err := opts.Endian()
+	if err != nil {
+		return nil, err
+	}
+	t, err := loadTemplates("typescript", opts, template.FuncMap{
+		"tsType":    tsType,
+		"tsRead":    tsRead,
+		"tsWrite":   tsWrite,
+		"tsDefault": tsDefault,
+		"isByte":    func(k FieldKind) bool { return k == ByteFieldKind || k == Uint8FieldKind },
+		"minWireSize": func(k FieldKind, typeName string) int {
+			return schema.MinWireSize(k, typeName)
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	code, err := executeTemplate(t, "module", &templateData{Schema: schema, Options: opts, Endian: endian})
+	if err != nil {
+		return nil, err
+	}
+	return map[string][]byte{strings.ToLower(schema.PackageName) + ".ts": code}, nil
+}
+
+// tsMethod returns the Reader/Writer method name of a fixed size kind.
+func tsMethod(k FieldKind) string {
+	if k.IsSigned() {
+		return fmt.Sprintf("i%d", k.Size()*8)
+	}
+	return fmt.Sprintf("u%d", k.Size()*8)
+}
+
+// tsRead returns the expression reading a single value of kind k from the reader r.
+func tsRead(k FieldKind, typeName string) string {
+	switch k {
+	case StringFieldKind:
+		return "r.string()"
+	case StructFieldKind:
+		return "read" + typeName + "(r)"
+	}
+	return "r." + tsMethod(k) + "()"
+}
+
+// tsWrite returns the statement writing the value v of kind k to the writer w.
+func tsWrite(k FieldKind, typeName, v string) string {
+	switch k {
+	case StringFieldKind:
+		return "w.string(" + v + ");"
+	case StructFieldKind:
+		return "write" + typeName + "(w, " + v + ");"
+	}
+	return "w." + tsMethod(k) + "(" + v + ");"
+}
+
+func tsElemType(k FieldKind, typeName string) string {
+	switch k {
+	case StringFieldKind:
+		return "string"
+	case StructFieldKind:
+		return typeName
+	case Uint64FieldKind, Int64FieldKind:
+		return "bigint"
+	}
+	return "number"
+}
+
+// tsType returns the declared type of a field.
+func tsType(f *FieldLayout) string {
+	switch f.kind {
+	case SliceFieldKind, ArrayFieldKind:
+		if f.subElementKind == ByteFieldKind || f.subElementKind == Uint8FieldKind {
+			return "Uint8Array"
+		}
+		return tsElemType(f.subElementKind, f.fieldType) + "[]"
+	}
+	return tsElemType(f.kind, f.fieldType)
+}
+
+func tsElemDefault(k FieldKind, typeName string) string {
+	switch k {
+	case StringFieldKind:
+		return `""`
+	case StructFieldKind:
+		return "new" + typeName + "()"
+	case Uint64FieldKind, Int64FieldKind:
+		return "0n"
+	}
+	return "0"
+}
+
+// tsDefault returns the initial value of a field.
+func tsDefault(f *FieldLayout) string {
+	isByte := f.subElementKind == ByteFieldKind || f.subElementKind == Uint8FieldKind
+	switch f.kind {
+	case SliceFieldKind:
+		if isByte {
+			return "new Uint8Array(0)"
+		}
+		return "[]"
+	case ArrayFieldKind:
+		if isByte {
+			return fmt.Sprintf("new Uint8Array(%d)", f.arrayLen)
+		}
+		return fmt.Sprintf("Array.from({ length: %d }, () => %s)", f.arrayLen, tsElemDefault(f.subElementKind, f.fieldType))
+	}
+	return tsElemDefault(f.kind, f.fieldType)
+}
@@ -0,0 +1,97 @@
+package generator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+func init() {
+	RegisterBackend("wireshark", wiresharkBackend{})
+}
+
+// wiresharkBackend generates a Wireshark Lua dissector. The options are "port",
+// the port the dissector is registered on, and "transport", which is tcp, udp or both.
+type wiresharkBackend struct{}
+
+func (wiresharkBackend) Generate(schema *Schema, opts Options) (map[string][]byte, error) {
+	endian, err := opts.Endian()
+	if err != nil {
+		return nil, err
+	}
+	port, err := strconv.ParseUint(opts.Get("port", "9000"), 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("invalid port: %s", err)
+	}
+	transport := strings.ToLower(opts.Get("transport", "tcp"))
+	if transport != "tcp" && transport != "udp" && transport != "both" {
+		return nil, fmt.Errorf("invalid transport %q, must be tcp, udp or both", transport)
+	}
+	name := strings.ToLower(schema.PackageName)
+	t, err := loadTemplates("wireshark", opts, template.FuncMap{
+		"protoName": func() string { return name },
+		"port":      func() uint64 { return port },
+		"transport": func() string { return transport },
+		"portLabel": func() string { return wsPortLabel(transport) },
+		"wsField": func(p *PacketLayout, f *FieldLayout) string {
+			return wsField(schema, name+"."+p.name+"."+f.name, f)
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	code, err := executeTemplate(t, "dissector", &templateData{Schema: schema, Options: opts, Endian: endian})
+	if err != nil {
+		return nil, err
+	}
+	return map[string][]byte{name + "_dissector.lua": code}, nil
+}
+
+// wsPortLabel returns the label of the port preference, e.g. "TCP port".
+func wsPortLabel(transport string) string {
+	if transport == "both" {
+		return "TCP and UDP port"
+	}
+	return strings.ToUpper(transport) + " port"
+}
+
+// wsValue returns the description of a single value of kind k, abbrev is the filter name
+// of its ProtoField and label its display name.
+func wsValue(abbrev, label string, k FieldKind, typeName string) string {
+	switch k {
+	case StringFieldKind:
+		return fmt.Sprintf("{ kind = \"string\", field = ProtoField.string(%q, %q) }", abbrev, label)
+	case StructFieldKind:
+		return fmt.Sprintf("{ kind = \"struct\", type = %q, field = ProtoField.none(%q, %q) }", typeName, abbrev, label)
+	}
+	typ, base := k.String(), "base.DEC"
+	if k == ByteFieldKind {
+		typ = "uint8"
+	}
+	if !k.IsSigned() && k.Size() > 1 {
+		base = "base.DEC_HEX"
+	}
+	return fmt.Sprintf("{ kind = \"fixed\", size = %d, field = ProtoField.%s(%q, %q, %s) }", k.Size(), typ, abbrev, label, base)
+}
+
+// wsField returns the Lua table describing how a field is dissected. Slices and arrays
+// carry the least wire size of an element, which bounds the element count of a slice.
+func wsField(schema *Schema, abbrev string, f *FieldLayout) string {
+	isByte := f.subElementKind == ByteFieldKind || f.subElementKind == Uint8FieldKind
+	switch f.kind {
+	case SliceFieldKind:
+		if isByte {
+			return fmt.Sprintf("{ kind = \"bytes\", field = ProtoField.bytes(%q, %q) }", abbrev, f.name)
+		}
+		return fmt.Sprintf("{ kind = \"slice\", min_size = %d, field = ProtoField.none(%q, %q), elem = %s }",
+			schema.MinWireSize(f.subElementKind, f.fieldType), abbrev, f.name, wsValue(abbrev+".item", f.name, f.subElementKind, f.fieldType))
+	case ArrayFieldKind:
+		if isByte {
+			return fmt.Sprintf("{ kind = \"fixed_bytes\", size = %d, field = ProtoField.bytes(%q, %q) }", f.arrayLen, abbrev, f.name)
+		}
+		return fmt.Sprintf("{ kind = \"array\", len = %d, min_size = %d, field = ProtoField.none(%q, %q), elem = %s }",
+			f.arrayLen, schema.MinWireSize(f.subElementKind, f.fieldType), abbrev, f.name, wsValue(abbrev+".item", f.name, f.subElementKind, f.fieldType))
+	}
+	return wsValue(abbrev, f.name, f.kind, f.fieldType)
+}
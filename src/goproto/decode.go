@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bytes"
+	"dynamic"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"generator"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"stream"
+	"strings"
+)
+
+// decodeCommand prints the frames of a hex dump or a binary file decoded by the protocol definition.
+func decodeCommand(args []string) error {
+	flags := flag.NewFlagSet("decode", flag.ExitOnError)
+	src := flags.String("src", "", "set protocol file path")
+	format := flags.String("format", "auto", "input format: auto|hex|bin, auto treats input of hex digits and spaces as hex")
+	opts := make(optionFlags)
+	flags.Var(opts, "opt", "option as key=value, may be repeated: endian, maxelements, maxstringbytes, maxtotalbytes")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: goproto decode -src protocol.go [flags] [file]\nreads standard input if file is missing or -")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if len(*src) == 0 {
+		return errors.New("decode: -src is required")
+	}
+	schema, err := generator.ParseSchema(*src)
+	if err != nil {
+		return err
+	}
+	endian, err := generator.Options(opts).Endian()
+	if err != nil {
+		return err
+	}
+	codec, err := newLimitedCodec(schema, generator.Options(opts))
+	if err != nil {
+		return err
+	}
+	data, err := readInput(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	if data, err = decodeInput(data, *format); err != nil {
+		return err
+	}
+	return decodeFrames(os.Stdout, codec, endian, data)
+}
+
+// newLimitedCodec returns a codec reading with the limits set by the options.
+func newLimitedCodec(schema *generator.Schema, opts generator.Options) (*dynamic.Codec, error) {
+	limits, err := opts.DecodeLimits()
+	if err != nil {
+		return nil, err
+	}
+	codec := dynamic.NewCodec(schema)
+	codec.Limits = limits
+	return codec, nil
+}
+
+func readInput(file string) ([]byte, error) {
+	if len(file) == 0 || file == "-" {
+		return ioutil.ReadAll(os.Stdin)
+	}
+	return ioutil.ReadFile(file)
+}
+
+// decodeInput converts hex text to bytes, spaces, colons, dashes and 0x prefixes are ignored.
+func decodeInput(data []byte, format string) ([]byte, error) {
+	isHex := func() bool {
+		for _, c := range data {
+			if !strings.ContainsRune("0123456789abcdefABCDEFxX: \t\r\n-", rune(c)) {
+				return false
+			}
+		}
+		return len(bytes.TrimSpace(data)) != 0
+	}
+	switch format {
+	case "bin":
+		return data, nil
+	case "auto":
+		if !isHex() {
+			return data, nil
+		}
+	case "hex":
+	default:
+		return nil, fmt.Errorf("unknown input format %q", format)
+	}
+	text := strings.NewReplacer("0x", "", "0X", "", ":", "", "-", "", " ", "", "\t", "", "\r", "", "\n", "").Replace(string(data))
+	return hex.DecodeString(text)
+}
+
+// decodeErrors is returned by the commands printing the errors next to the frames they belong
+// to, it only gives their number so that the messages are not repeated.
+type decodeErrors int
+
+func (n decodeErrors) Error() string {
+	if n == 1 {
+		return "1 decoding error"
+	}
+	return fmt.Sprintf("%d decoding errors", int(n))
+}
+
+// decodeFrames splits data into frames by the length in the packet header and prints each of them.
+// Decoding goes on with the next frame after an error in a body, the error is printed below the
+// frame and counted in the decodeErrors returned at the end. An invalid header stops decoding.
+func decodeFrames(w io.Writer, codec *dynamic.Codec, endian string, data []byte) error {
+	var failed decodeErrors
+	for offset, index := 0, 0; offset < len(data); index++ {
+		if left := len(data) - offset; left < generator.PacketHeaderSize {
+			return fmt.Errorf("frame %d at offset %d: %d bytes left, the packet header needs %d", index, offset, left, generator.PacketHeaderSize)
+		}
+		var header dynamic.Header
+		header.Read(dynamic.NewReadStream(endian, data[offset:offset+generator.PacketHeaderSize]))
+		length := int(header.Len)
+		if length < generator.PacketHeaderLength || length > len(data)-offset {
+			return fmt.Errorf("frame %d at offset %d: invalid length %d, %d bytes left", index, offset, length, len(data)-offset)
+		}
+		if err := codec.Limits.CheckTotal(header.Len); err != nil {
+			return fmt.Errorf("frame %d at offset %d: %s", index, offset, err)
+		}
+		fmt.Fprintf(w, "frame %d at offset %d, %d bytes\n", index, offset, length)
+		if err := decodeFrame(w, codec, dynamic.NewReadStream(endian, data[offset:offset+length]), offset); err != nil {
+			fmt.Fprintf(w, "  error: %s\n", err)
+			failed++
+		}
+		offset += length
+	}
+	if failed != 0 {
+		return failed
+	}
+	return nil
+}
+
+// framePrinter prints the fields of a decoded frame indented by their depth.
+type framePrinter struct {
+	w      io.Writer
+	schema *generator.Schema
+	depth  int
+}
+
+func (d *framePrinter) line(format string, args ...interface{}) {
+	fmt.Fprintf(d.w, "%s%s\n", strings.Repeat("  ", d.depth), fmt.Sprintf(format, args...))
+}
+
+// decodeFrame prints the frame read from s, base is its offset in the input.
+// On an error in the body the fields before the failing one are printed.
+func decodeFrame(w io.Writer, codec *dynamic.Codec, s stream.ReadStream, base int) error {
+	schema := codec.Schema()
+	d := &framePrinter{w: w, schema: schema, depth: 1}
+	var header dynamic.Header
+	header.Read(s)
+	packet := schema.LookupID(header.PacketType)
+	typeName := fmt.Sprintf("unknown (0x%08x)", header.PacketType)
+	if packet != nil {
+		typeName = fmt.Sprintf("%s (0x%08x)", packet.IDName(), header.PacketType)
+	}
+	d.line("Header: {ID: %d, PacketType: %s, Len: %d, Version: %d, Ack: %d, Token: %d}",
+		header.ID, typeName, header.Len, header.Version, header.Ack, header.Token)
+	if packet == nil {
+		return fmt.Errorf("unknown packet type 0x%08x", header.PacketType)
+	}
+	d.line("%s", packet.Name())
+	d.depth++
+	fields, err := codec.Read(packet, s)
+	d.fields(packet, fields)
+	if err != nil {
+		if e, ok := err.(*dynamic.DecodeError); ok {
+			e.Offset += base
+		}
+		return err
+	}
+	if left := s.Left(); left != generator.PacketPadding {
+		return fmt.Errorf("%d bytes after the last field at offset %d, Len must count %d reserved bytes", left, base+s.Size()-left, generator.PacketPadding)
+	}
+	return nil
+}
+
+// fields prints the fields of p in definition order, up to the first one missing.
+func (d *framePrinter) fields(p *generator.PacketLayout, fields map[string]interface{}) {
+	for _, f := range p.Fields() {
+		v, ok := fields[f.Name()]
+		if !ok {
+			return
+		}
+		d.field(f, v)
+	}
+}
+
+func (d *framePrinter) field(f *generator.FieldLayout, v interface{}) {
+	switch v := v.(type) {
+	case []byte:
+		d.line("%s: %s (%d bytes)", f.Name(), hex.EncodeToString(v), len(v))
+	case []interface{}:
+		if f.ElemKind() != generator.StructFieldKind {
+			values := make([]string, len(v))
+			for i, elem := range v {
+				values[i] = formatValue(elem)
+			}
+			d.line("%s: [%s]", f.Name(), strings.Join(values, ", "))
+			return
+		}
+		d.line("%s: %d elements", f.Name(), len(v))
+		d.depth++
+		for i, elem := range v {
+			d.structValue(fmt.Sprintf("[%d]", i), f.TypeName(), elem)
+		}
+		d.depth--
+	case map[string]interface{}:
+		d.structValue(f.Name(), f.TypeName(), v)
+	default:
+		d.line("%s: %s", f.Name(), formatValue(v))
+	}
+}
+
+func (d *framePrinter) structValue(label, typeName string, v interface{}) {
+	d.line("%s: %s", label, typeName)
+	if p := d.schema.Lookup(typeName); p != nil {
+		d.depth++
+		d.fields(p, v.(map[string]interface{}))
+		d.depth--
+	}
+}
+
+// formatValue returns the text of a string or an integer.
+func formatValue(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return strconv.Quote(s)
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"dynamic"
+	"encoding/hex"
+	"errors"
+	"generator"
+	"strings"
+	"testing"
+)
+
+func TestDecodeInput(t *testing.T) {
+	tests := []struct {
+		in, format string
+		want       string
+		err        bool
+	}{
+		{"0a0B ff", "auto", "0a0bff", false},
+		{"0x0a 0x0b\n0c:0d-0e", "auto", "0a0b0c0d0e", false},
+		{"0a0b\x00", "auto", "30613062" + "00", false},
+		{"  \n", "auto", "20200a", false},
+		{"0a0b", "bin", "30613062", false},
+		{"0a0", "hex", "", true},
+		{"zz", "hex", "", true},
+		{"0a", "text", "", true},
+	}
+	for _, tt := range tests {
+		got, err := decodeInput([]byte(tt.in), tt.format)
+		if err != nil != tt.err {
+			t.Errorf("decodeInput(%q, %s): error %v, want error %v", tt.in, tt.format, err, tt.err)
+			continue
+		}
+		if !tt.err && hex.EncodeToString(got) != tt.want {
+			t.Errorf("decodeInput(%q, %s) = %x, want %s", tt.in, tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestDecodeFrames(t *testing.T) {
+	schema, err := generator.ParseSchemaSource([]byte(pcapTestSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	frame := func(name string) []byte {
+		data, err := encodePacket(schema, "big", map[string]interface{}{"type": "HELLO", "Name": name})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+	a, b := frame("a"), frame("bb")
+	unknown := append([]byte(nil), a...)
+	unknown[7] = 9
+	truncated := append([]byte(nil), b...)
+	truncated[26] = 1
+	tests := []struct {
+		name string
+		data []byte
+		// output holds text expected once in the output, in this order
+		output []string
+		// errors is the number of frames failing to decode, -1 for an error stopping decoding
+		errors int
+	}{
+		{"frames", concat(a, b), []string{"frame 0 at offset 0", `Name: "a"`, "frame 1 at offset 41", `Name: "bb"`}, 0},
+		{"unknown type", concat(unknown, b), []string{"PacketType: unknown (0x00000009)", "  error: unknown packet type 0x00000009", `Name: "bb"`}, 1},
+		{"bad bodies", concat(truncated, a, unknown), []string{"frame 0", "  error: ", "frame 1", `Name: "a"`, "frame 2", "  error: unknown packet type"}, 2},
+		{"short header", concat(a, a[:10]), []string{`Name: "a"`}, -1},
+		{"invalid length", concat(b, []byte{0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 200}, make([]byte, 24)), []string{`Name: "bb"`}, -1},
+	}
+	codec := dynamic.NewCodec(schema)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := decodeFrames(&out, codec, "big", tt.data)
+			var failed decodeErrors
+			switch {
+			case tt.errors < 0:
+				if err == nil || errors.As(err, &failed) {
+					t.Errorf("got %v, want an error stopping decoding", err)
+				}
+			case tt.errors == 0:
+				if err != nil {
+					t.Error(err)
+				}
+			case !errors.As(err, &failed) || int(failed) != tt.errors:
+				t.Errorf("got %v, want %d decoding errors", err, tt.errors)
+			}
+			text := out.String()
+			for _, s := range tt.output {
+				i := strings.Index(text, s)
+				if i < 0 {
+					t.Fatalf("output misses %q\n%s", s, out.String())
+				}
+				text = text[i+len(s):]
+			}
+			if err != nil && strings.Contains(out.String(), err.Error()) {
+				t.Errorf("the returned error %q is in the output too\n%s", err, out.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bytes"
+	"dynamic"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"generator"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+// encodeCommand encodes packets described in JSON or YAML, in the form the generated
+// MarshalJSON writes: the ID name as "type", an optional "header" and the fields by name.
+func encodeCommand(args []string) error {
+	flags := flag.NewFlagSet("encode", flag.ExitOnError)
+	src := flags.String("src", "", "set protocol file path")
+	format := flags.String("format", "hex", "output format: hex|bin, hex writes a line per packet")
+	out := flags.String("o", "", "output file, standard output by default")
+	opts := make(optionFlags)
+	flags.Var(opts, "opt", "option as key=value, may be repeated: endian")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: goproto encode -src protocol.go [flags] [file]\nreads standard input if file is missing or -, the input is JSON if it starts with { or [, YAML otherwise")
+		flags.PrintDefaults()
+		fmt.Fprintln(flags.Output(), yamlSubset)
+	}
+	flags.Parse(args)
+	if len(*src) == 0 {
+		return errors.New("encode: -src is required")
+	}
+	if *format != "hex" && *format != "bin" {
+		return fmt.Errorf("unknown output format %q", *format)
+	}
+	schema, err := generator.ParseSchema(*src)
+	if err != nil {
+		return err
+	}
+	endian, err := generator.Options(opts).Endian()
+	if err != nil {
+		return err
+	}
+	data, err := readInput(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	packets, err := parsePacketDescriptions(data)
+	if err != nil {
+		return err
+	}
+	var output bytes.Buffer
+	for i, packet := range packets {
+		frame, err := encodePacket(schema, endian, packet)
+		if err != nil {
+			return fmt.Errorf("packet %d: %s", i, err)
+		}
+		if *format == "hex" {
+			output.WriteString(hex.EncodeToString(frame))
+			output.WriteByte('\n')
+		} else {
+			output.Write(frame)
+		}
+	}
+	if len(*out) == 0 {
+		_, err = os.Stdout.Write(output.Bytes())
+		return err
+	}
+	return ioutil.WriteFile(*out, output.Bytes(), os.ModePerm)
+}
+
+// parsePacketDescriptions returns the packet objects of the input, which is a sequence
+// of JSON values or YAML documents, each of them is an object or a list of objects.
+func parsePacketDescriptions(data []byte) ([]interface{}, error) {
+	var values []interface{}
+	if trimmed := bytes.TrimSpace(data); len(trimmed) != 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+		decoder := json.NewDecoder(bytes.NewReader(trimmed))
+		decoder.UseNumber()
+		for {
+			var v interface{}
+			if err := decoder.Decode(&v); err == io.EOF {
+				break
+			} else if err != nil {
+				return nil, err
+			}
+			values = append(values, v)
+		}
+	} else {
+		var err error
+		if values, err = parseYAML(data); err != nil {
+			return nil, err
+		}
+	}
+	var packets []interface{}
+	for _, v := range values {
+		if list, ok := v.([]interface{}); ok {
+			packets = append(packets, list...)
+		} else {
+			packets = append(packets, v)
+		}
+	}
+	return packets, nil
+}
+
+// encodePacket encodes a packet object. The header members override the computed values,
+// so that a wrong Len or PacketType can be crafted on purpose.
+func encodePacket(schema *generator.Schema, endian string, description interface{}) ([]byte, error) {
+	object, ok := description.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected an object, got %s", dynamic.Kind(resolveYAML(description)))
+	}
+	codec := dynamic.NewCodec(schema)
+	name, _ := resolveYAML(object["type"]).(string)
+	packet, err := codec.New(name)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range object {
+		if key != "type" && key != "header" {
+			packet.Fields[key] = value
+		}
+	}
+	resolveYAMLFields(schema, packet.Layout, packet.Fields)
+	if err = codec.AdjustLength(packet); err != nil {
+		return nil, err
+	}
+	if v := resolveYAML(object["header"]); v != nil {
+		members, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("header: expected an object, got %s", dynamic.Kind(v))
+		}
+		header := map[string]*uint32{
+			"ID":         &packet.ID,
+			"PacketType": &packet.PacketType,
+			"Len":        &packet.Len,
+			"Version":    &packet.Version,
+			"Ack":        &packet.Ack,
+			"Token":      &packet.Token,
+		}
+		for key, member := range members {
+			field, ok := header[key]
+			if !ok {
+				return nil, fmt.Errorf("header: unknown field %q", key)
+			}
+			value, err := dynamic.ToInteger(member, generator.Uint32FieldKind)
+			if err != nil {
+				return nil, fmt.Errorf("header.%s: %s", key, err)
+			}
+			*field = uint32(value)
+		}
+	}
+	return codec.Marshal(packet, endian)
+}
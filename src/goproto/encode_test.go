@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/hex"
+	"generator"
+	"testing"
+)
+
+const defaultsSchema = `package protocol
+
+type Person struct {
+	Name string ` + "`goproto:\"default=anon\"`" + `
+	Age  uint32 ` + "`goproto:\"default=5\"`" + `
+}
+
+type Group struct {
+	Lead  Person
+	Level int16 ` + "`goproto:\"default=-0x10\"`" + `
+}
+
+// @Packet: DEFAULTS, 0x00000003
+type Defaults struct {
+	Count  uint8 ` + "`goproto:\"default=3\"`" + `
+	Label  string ` + "`goproto:\"default=x y\"`" + `
+	Fixed  [2]Person
+	Group  Group
+	People []Person
+	Plain  uint16
+}
+`
+
+func TestEncodeDefaults(t *testing.T) {
+	schema, err := generator.ParseSchemaSource([]byte(defaultsSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// want is written by the code the go backend generates from defaultsSchema,
+	// for the packet PacketFromJSON returns.
+	tests := []struct {
+		name string
+		in   string
+		want map[string]string
+	}{
+		{
+			"nested", `{"type":"DEFAULTS","Label":"set","Fixed":[{"Name":"a"},{"Name":"","Age":0}],"Group":{"Lead":{"Age":7}},"People":[{},{"Name":"b","Age":0}]}`,
+			map[string]string{
+				"big": "00000000000000030000006200000000000000000000000003000000037365740000000161000000050000000000000000" +
+					"00000004616e6f6e00000007fff00000000200000000000000000000000162000000000000000000000000000000000000",
+				"little": "00000000030000006200000000000000000000000000000003030000007365740100000061050000000000000000000000" +
+					"04000000616e6f6e07000000f0ff0200000000000000000000000100000062000000000000000000000000000000000000",
+			},
+		},
+		{
+			"missing", `{"type":"DEFAULTS","People":[{"Name":"c"}]}`,
+			map[string]string{
+				"big": "0000000000000003000000610000000000000000000000000300000003782079000000" +
+					"04616e6f6e0000000500000004616e6f6e0000000500000004616e6f6e00000005fff0000000010000000163000000000000000000000000000000000000",
+				"little": "0000000003000000610000000000000000000000000000000303000000782079040000" +
+					"00616e6f6e0500000004000000616e6f6e0500000004000000616e6f6e05000000f0ff010000000100000063000000000000000000000000000000000000",
+			},
+		},
+	}
+	for _, tt := range tests {
+		descriptions, err := parsePacketDescriptions([]byte(tt.in))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, endian := range []string{"big", "little"} {
+			data, err := encodePacket(schema, endian, descriptions[0])
+			if err != nil {
+				t.Fatalf("%s %s: %v", tt.name, endian, err)
+			}
+			if got := hex.EncodeToString(data); got != tt.want[endian] {
+				t.Errorf("%s %s: encode differs from the generated code\ngot  %s\nwant %s", tt.name, endian, got, tt.want[endian])
+			}
+		}
+	}
+}
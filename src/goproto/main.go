@@ -2,22 +2,122 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"generator"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
 )
 
+type optionFlags generator.Options
+
+func (o optionFlags) String() string { return "" }
+
+func (o optionFlags) Set(value string) error {
+	kv := strings.SplitN(value, "=", 2)
+	if len(kv) == 1 {
+		o[kv[0]] = "true"
+	} else {
+		o[kv[0]] = kv[1]
+	}
+	return nil
+}
+
+// commands maps the subcommand given as the first argument to its implementation,
+// without a subcommand the flags generate code as before.
+var commands = map[string]func(args []string) error{
+	"export-proto": exportProto,
+	"import-proto": importProto,
+	"decode":       decodeCommand,
+	"encode":       encodeCommand,
+	"pcap":         pcapCommand,
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		if command, ok := commands[os.Args[1]]; ok {
+			if err := command(os.Args[2:]); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
 	src := flag.String("src", "", "set protocol file path")
-	dest := flag.String("dest", "", "protocol code's file")
+	dest := flag.String("dest", "", "protocol code's file, or the output directory if the backend generates several files")
+	lang := flag.String("lang", "go", "target language: "+strings.Join(generator.BackendNames(), "|"))
+	templates := flag.String("templates", "", templatesUsage("<lang>"))
+	opts := make(optionFlags)
+	flag.Var(opts, "opt", "backend option as key=value, may be repeated")
 	flag.Parse()
+	if len(*templates) != 0 {
+		opts["templates"] = *templates
+	}
 	if len(*src) != 0 && len(*dest) != 0 {
-		data, err := generator.Generate(*src)
+		files, err := generator.GenerateWith(*lang, *src, generator.Options(opts))
 		if err != nil {
-			println(err.Error())
-			return
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if err = writeFiles(*dest, files); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
 		}
-		ioutil.WriteFile(*dest, data, os.ModePerm)
-		println("Complete!")
+		fmt.Println("Complete!")
+	}
+}
+
+// templatesUsage is the help of the -templates flags, lang names the subdirectory the
+// overrides are read from.
+func templatesUsage(lang string) string {
+	return "directory whose " + lang + "/*.tmpl files override the built-in templates of the language"
+}
+
+// writeFiles writes a single file to dest, several files are written into the directory dest.
+// If dest names a file and a single one of several files, apart from tests, has its extension,
+// that file is written to dest and the others next to it, e.g. -dest protocol.go with -opt tests.
+// Missing parent directories are created.
+func writeFiles(dest string, files map[string][]byte) error {
+	if info, err := os.Stat(dest); err != nil || !info.IsDir() {
+		if len(files) == 1 {
+			for _, data := range files {
+				return writeFile(dest, data)
+			}
+		}
+		var main []string
+		for name := range files {
+			if ext := filepath.Ext(dest); len(ext) != 0 && filepath.Ext(name) == ext && !strings.HasSuffix(name, "_test"+ext) {
+				main = append(main, name)
+			}
+		}
+		if len(main) == 1 {
+			for name, data := range files {
+				path := filepath.Join(filepath.Dir(dest), name)
+				if name == main[0] {
+					path = dest
+				}
+				if err := writeFile(path, data); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+	if err := os.MkdirAll(dest, os.ModePerm); err != nil {
+		return err
+	}
+	for name, data := range files {
+		if err := writeFile(filepath.Join(dest, name), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
 	}
+	return ioutil.WriteFile(path, data, os.ModePerm)
 }
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"dynamic"
+	"errors"
+	"flag"
+	"fmt"
+	"generator"
+	"io"
+	"os"
+	"time"
+)
+
+// pcapCommand decodes the packets of the TCP streams on a port in a pcap or pcapng file.
+func pcapCommand(args []string) error {
+	flags := flag.NewFlagSet("pcap", flag.ExitOnError)
+	src := flags.String("src", "", "set protocol file path")
+	port := flags.Uint("port", 0, "TCP port of the server")
+	utc := flags.Bool("utc", false, "print timestamps in UTC instead of local time")
+	opts := make(optionFlags)
+	flags.Var(opts, "opt", "option as key=value, may be repeated: endian, maxelements, maxstringbytes, maxtotalbytes")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: goproto pcap -src protocol.go -port 9000 [flags] capture.pcap")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if len(*src) == 0 || *port == 0 || *port > 0xffff || flags.NArg() != 1 {
+		flags.Usage()
+		return errors.New("pcap: -src, -port and the capture file are required")
+	}
+	schema, err := generator.ParseSchema(*src)
+	if err != nil {
+		return err
+	}
+	endian, err := generator.Options(opts).Endian()
+	if err != nil {
+		return err
+	}
+	codec, err := newLimitedCodec(schema, generator.Options(opts))
+	if err != nil {
+		return err
+	}
+	file, err := os.Open(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	reader, err := newCaptureReader(file)
+	if err != nil {
+		return err
+	}
+	a := &streamAssembler{
+		w:      os.Stdout,
+		codec:  codec,
+		endian: endian,
+		port:   uint16(*port),
+		utc:    *utc,
+		flows:  make(map[string]*tcpFlow),
+		// the limits are not options, they only keep the memory of a broken capture bounded
+		maxPendingBytes:    defaultMaxPendingBytes,
+		maxPendingSegments: defaultMaxPendingSegments,
+	}
+	for {
+		packet, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if segment, ok := parseTCP(packet.LinkType, packet.Data); ok {
+			a.add(packet.Time, segment)
+		}
+	}
+	a.finish()
+	if a.failed != 0 {
+		return a.failed
+	}
+	return nil
+}
+
+// tcpFlow is one direction of a TCP connection.
+type tcpFlow struct {
+	name    string
+	client  bool
+	started bool
+	next    uint32
+	// pending holds the segments received after a gap by their sequence number,
+	// pendingBytes is the size of their payloads.
+	pending      map[uint32][]byte
+	pendingBytes int
+	buf          []byte
+	broken       bool
+}
+
+// The segments a flow keeps after a gap are limited, so that a capture missing data does not
+// hold the rest of a long connection in memory. The defaults are the limits of the command.
+const (
+	defaultMaxPendingBytes    = 64 << 20
+	defaultMaxPendingSegments = 65536
+)
+
+// streamAssembler puts the TCP segments of each direction in order and splits the
+// byte stream into frames by the packet header length.
+type streamAssembler struct {
+	w          io.Writer
+	codec      *dynamic.Codec
+	endian     string
+	port       uint16
+	utc        bool
+	flows      map[string]*tcpFlow
+	order      []*tcpFlow
+	fragmented bool
+	failed     decodeErrors
+	// maxPendingBytes and maxPendingSegments limit the pending segments of a flow, a flow
+	// going over them is broken. 0 means no limit.
+	maxPendingBytes    int
+	maxPendingSegments int
+}
+
+func (a *streamAssembler) add(t time.Time, s tcpSegment) {
+	if s.Fragmented {
+		if !a.fragmented {
+			fmt.Fprintln(a.w, "warning: fragmented IP packets are not reassembled and skipped")
+			a.fragmented = true
+		}
+		return
+	}
+	if s.SrcPort != a.port && s.DstPort != a.port {
+		return
+	}
+	name := fmt.Sprintf("%s -> %s", joinHostPort(s.Src, s.SrcPort), joinHostPort(s.Dst, s.DstPort))
+	f := a.flows[name]
+	if f == nil || (s.SYN && f.started) {
+		// a SYN on a known flow starts a new connection reusing the addresses
+		if f != nil {
+			a.finishFlow(f)
+		}
+		f = &tcpFlow{name: name, client: s.DstPort == a.port, pending: make(map[uint32][]byte)}
+		a.flows[name] = f
+		a.order = append(a.order, f)
+	}
+	if s.SYN {
+		f.started, f.next = true, s.Seq+1
+		return
+	}
+	if !f.started {
+		// the capture began in the middle of the connection
+		f.started, f.next = true, s.Seq
+	}
+	if len(s.Payload) == 0 || f.broken {
+		return
+	}
+	if diff := int32(s.Seq - f.next); diff > 0 {
+		if old := f.pending[s.Seq]; len(old) < len(s.Payload) {
+			f.pending[s.Seq] = append([]byte(nil), s.Payload...)
+			f.pendingBytes += len(s.Payload) - len(old)
+			a.checkPending(f)
+		}
+		return
+	} else if int(-diff) >= len(s.Payload) {
+		return
+	} else {
+		a.deliver(t, f, s.Payload[-diff:])
+	}
+	for found := true; found && !f.broken; {
+		found = false
+		for seq, payload := range f.pending {
+			diff := int32(seq - f.next)
+			if diff > 0 {
+				continue
+			}
+			delete(f.pending, seq)
+			f.pendingBytes -= len(payload)
+			if int(-diff) < len(payload) {
+				a.deliver(t, f, payload[-diff:])
+				found = true
+				break
+			}
+		}
+	}
+}
+
+func joinHostPort(host string, port uint16) string {
+	for _, c := range host {
+		if c == ':' {
+			return fmt.Sprintf("[%s]:%d", host, port)
+		}
+	}
+	return fmt.Sprintf("%s:%d", host, port)
+}
+
+// checkPending breaks the flow if its pending segments exceed the limits.
+func (a *streamAssembler) checkPending(f *tcpFlow) {
+	size, segments := f.pendingBytes, len(f.pending)
+	if (a.maxPendingBytes == 0 || size <= a.maxPendingBytes) && (a.maxPendingSegments == 0 || segments <= a.maxPendingSegments) {
+		return
+	}
+	a.fail("", fmt.Errorf("%s: %d bytes in %d segments wait for the data missing at sequence %d, the rest of the stream is skipped",
+		f.name, size, segments, f.next))
+	f.broken, f.buf, f.pending, f.pendingBytes = true, nil, nil, 0
+}
+
+// deliver appends in order data to the flow and prints the frames completed by it.
+func (a *streamAssembler) deliver(t time.Time, f *tcpFlow, data []byte) {
+	f.next += uint32(len(data))
+	f.buf = append(f.buf, data...)
+	for len(f.buf) >= generator.PacketHeaderSize {
+		var header dynamic.Header
+		header.Read(dynamic.NewReadStream(a.endian, f.buf[:generator.PacketHeaderSize]))
+		length := int(header.Len)
+		err := a.codec.Limits.CheckTotal(header.Len)
+		if length < generator.PacketHeaderLength {
+			err = fmt.Errorf("invalid frame length %d", length)
+		}
+		if err != nil {
+			a.fail("", fmt.Errorf("%s: %s, the rest of the stream is skipped", f.name, err))
+			f.broken, f.buf, f.pending, f.pendingBytes = true, nil, nil, 0
+			return
+		}
+		if len(f.buf) < length {
+			return
+		}
+		if a.utc {
+			t = t.UTC()
+		}
+		direction := "server"
+		if f.client {
+			direction = "client"
+		}
+		fmt.Fprintf(a.w, "%s %s %s, %d bytes\n", t.Format("2006-01-02 15:04:05.000000"), direction, f.name, length)
+		if err := decodeFrame(a.w, a.codec, dynamic.NewReadStream(a.endian, f.buf[:length]), 0); err != nil {
+			a.fail("  ", err)
+		}
+		f.buf = f.buf[length:]
+	}
+}
+
+// fail prints an error with the given indent and counts it.
+func (a *streamAssembler) fail(indent string, err error) {
+	fmt.Fprintf(a.w, "%serror: %s\n", indent, err)
+	a.failed++
+}
+
+func (a *streamAssembler) finishFlow(f *tcpFlow) {
+	if len(f.buf) != 0 {
+		fmt.Fprintf(a.w, "warning: %s: %d bytes of an incomplete frame at the end\n", f.name, len(f.buf))
+	}
+	if len(f.pending) != 0 {
+		gap, rest := -1, 0
+		for seq, payload := range f.pending {
+			if d := int(seq - f.next); gap < 0 || d < gap {
+				gap = d
+			}
+			rest += len(payload)
+		}
+		fmt.Fprintf(a.w, "warning: %s: %d bytes were not captured, %d bytes after the gap are not decoded\n", f.name, gap, rest)
+	}
+}
+
+func (a *streamAssembler) finish() {
+	for _, f := range a.order {
+		if a.flows[f.name] == f {
+			a.finishFlow(f)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bytes"
+	"dynamic"
+	"generator"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const pcapTestSchema = `package protocol
+
+// @Packet: HELLO, 0x00000001
+type Hello struct {
+	Name string
+}
+`
+
+// testSegment is a segment of the client 10.0.0.1:40000 to the server port 9000, or of
+// the server to the client.
+func testSegment(server bool, seq uint32, syn bool, payload []byte) tcpSegment {
+	s := tcpSegment{Src: "10.0.0.1", Dst: "10.0.0.2", SrcPort: 40000, DstPort: 9000, Seq: seq, SYN: syn, Payload: payload}
+	if server {
+		s.Src, s.Dst, s.SrcPort, s.DstPort = s.Dst, s.Src, s.DstPort, s.SrcPort
+	}
+	return s
+}
+
+func TestStreamAssembler(t *testing.T) {
+	schema, err := generator.ParseSchemaSource([]byte(pcapTestSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	frame := func(name string) []byte {
+		data, err := encodePacket(schema, "big", map[string]interface{}{"type": "HELLO", "Name": name})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+	a, b, c := frame("a"), frame("bb"), frame("ccc")
+	ab := concat(a, b)
+	invalid := make([]byte, 24)
+	invalid[11] = 10
+	huge := make([]byte, 24)
+	huge[8] = 0x08
+	tests := []struct {
+		name     string
+		segments []tcpSegment
+		// want is the names decoded in order
+		want []string
+		// output holds text expected in the output
+		output []string
+		err    bool
+	}{
+		{"in order", []tcpSegment{
+			testSegment(false, 99, true, nil),
+			testSegment(false, 100, false, a),
+			testSegment(false, 100+uint32(len(a)), false, b),
+		}, []string{"a", "bb"}, []string{"client 10.0.0.1:40000 -> 10.0.0.2:9000"}, false},
+		{"split frames", []tcpSegment{
+			testSegment(true, 0, false, ab[:10]),
+			testSegment(true, 10, false, ab[10:50]),
+			testSegment(true, 50, false, ab[50:]),
+		}, []string{"a", "bb"}, []string{"server 10.0.0.2:9000 -> 10.0.0.1:40000"}, false},
+		{"out of order", []tcpSegment{
+			testSegment(false, 1000, false, c),
+			testSegment(false, 1000+uint32(len(c)+len(a)), false, b),
+			testSegment(false, 1000+uint32(len(c)), false, a),
+		}, []string{"ccc", "a", "bb"}, nil, false},
+		{"overlapping", []tcpSegment{
+			testSegment(false, 0, false, ab[:30]),
+			testSegment(false, 20, false, ab[20:60]),
+			testSegment(false, 10, false, ab[10:40]),
+			testSegment(false, 50, false, ab[50:]),
+		}, []string{"a", "bb"}, nil, false},
+		{"overlapping pending", []tcpSegment{
+			testSegment(false, 0, false, ab[:5]),
+			testSegment(false, 30, false, ab[30:]),
+			testSegment(false, 20, false, ab[20:45]),
+			testSegment(false, 5, false, ab[5:20]),
+		}, []string{"a", "bb"}, nil, false},
+		{"retransmission", []tcpSegment{
+			testSegment(false, 0, false, a),
+			testSegment(false, 0, false, a),
+			testSegment(false, uint32(len(a)), false, b),
+		}, []string{"a", "bb"}, nil, false},
+		{"sequence wraps", []tcpSegment{
+			testSegment(false, 0xfffffff0, true, nil),
+			testSegment(false, 0xfffffff1, false, ab),
+		}, []string{"a", "bb"}, nil, false},
+		{"syn reuses the addresses", []tcpSegment{
+			testSegment(false, 99, true, nil),
+			testSegment(false, 100, false, concat(a, b[:10])),
+			testSegment(false, 5000, true, nil),
+			testSegment(false, 5001, false, c),
+		}, []string{"a", "ccc"}, []string{"10 bytes of an incomplete frame"}, false},
+		{"gap", []tcpSegment{
+			testSegment(false, 0, false, a),
+			testSegment(false, uint32(len(a)+5), false, b),
+		}, []string{"a"}, []string{"5 bytes were not captured, " + strconv.Itoa(len(b)) + " bytes after the gap"}, false},
+		{"both directions", []tcpSegment{
+			testSegment(false, 0, false, a),
+			testSegment(true, 0, false, b),
+			testSegment(false, uint32(len(a)), false, c),
+		}, []string{"a", "bb", "ccc"}, nil, false},
+		{"other port", []tcpSegment{
+			{Src: "10.0.0.1", Dst: "10.0.0.2", SrcPort: 40000, DstPort: 80, Payload: a},
+		}, nil, nil, false},
+		{"fragmented", []tcpSegment{
+			{Fragmented: true},
+			testSegment(false, 0, false, a),
+		}, []string{"a"}, []string{"fragmented IP packets are not reassembled"}, false},
+		{"invalid length", []tcpSegment{
+			testSegment(false, 0, false, a),
+			testSegment(false, uint32(len(a)), false, invalid),
+			testSegment(false, uint32(len(a)+len(invalid)), false, b),
+		}, []string{"a"}, []string{"invalid frame length 10, the rest of the stream is skipped"}, true},
+		{"length over MaxTotalBytes", []tcpSegment{
+			testSegment(false, 0, false, huge),
+		}, nil, []string{"length 134217728 exceeds MaxTotalBytes 67108864"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			a := &streamAssembler{w: &out, codec: dynamic.NewCodec(schema), endian: "big", port: 9000, flows: make(map[string]*tcpFlow)}
+			for _, s := range tt.segments {
+				a.add(time.Unix(0, 0), s)
+			}
+			a.finish()
+			if (a.failed != 0) != tt.err {
+				t.Errorf("%d errors, want error %v", a.failed, tt.err)
+			}
+			var names []string
+			for _, m := range regexp.MustCompile(`Name: "(.*)"`).FindAllStringSubmatch(out.String(), -1) {
+				names = append(names, m[1])
+			}
+			if strings.Join(names, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("decoded %q, want %q\n%s", names, tt.want, out.String())
+			}
+			for _, text := range tt.output {
+				if !strings.Contains(out.String(), text) {
+					t.Errorf("output misses %q\n%s", text, out.String())
+				}
+			}
+		})
+	}
+}
+
+func TestStreamAssemblerPendingLimits(t *testing.T) {
+	schema, err := generator.ParseSchemaSource([]byte(pcapTestSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var frames [][]byte
+	for _, name := range []string{"a", "bb", "ccc", "dddd"} {
+		frame, err := encodePacket(schema, "big", map[string]interface{}{"type": "HELLO", "Name": name})
+		if err != nil {
+			t.Fatal(err)
+		}
+		frames = append(frames, frame)
+	}
+	data := concat(frames...)
+	a, b, c := len(frames[0]), len(frames[1]), len(frames[2])
+	// the first frame arrives last, the others wait for it, a retransmission must not count twice
+	segments := []tcpSegment{
+		testSegment(false, 99, true, nil),
+		testSegment(false, 100+uint32(a), false, data[a:a+b]),
+		testSegment(false, 100+uint32(a), false, data[a:a+b]),
+		testSegment(false, 100+uint32(a+b), false, data[a+b:a+b+c]),
+		testSegment(false, 100+uint32(a+b+c), false, data[a+b+c:]),
+		testSegment(false, 100, false, data[:a]),
+	}
+	tests := []struct {
+		name               string
+		maxBytes, segments int
+		want               string
+		err                bool
+	}{
+		{"no limits", 0, 0, "a,bb,ccc,dddd", false},
+		{"within the limits", len(data) - a, 3, "a,bb,ccc,dddd", false},
+		{"too many bytes", len(data) - a - 1, 0, "", true},
+		{"too many segments", 0, 2, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			assembler := &streamAssembler{w: &out, codec: dynamic.NewCodec(schema), endian: "big", port: 9000, flows: make(map[string]*tcpFlow),
+				maxPendingBytes: tt.maxBytes, maxPendingSegments: tt.segments}
+			for _, s := range segments {
+				assembler.add(time.Unix(0, 0), s)
+			}
+			assembler.finish()
+			var names []string
+			for _, m := range regexp.MustCompile(`Name: "(.*)"`).FindAllStringSubmatch(out.String(), -1) {
+				names = append(names, m[1])
+			}
+			if strings.Join(names, ",") != tt.want || (assembler.failed != 0) != tt.err {
+				t.Errorf("decoded %q with %d errors, want %q and error %v\n%s", names, assembler.failed, tt.want, tt.err, out.String())
+			}
+			if tt.err && !strings.Contains(out.String(), "wait for the data missing at sequence 100, the rest of the stream is skipped") {
+				t.Errorf("output misses the broken flow\n%s", out.String())
+			}
+			for _, f := range assembler.flows {
+				if f.pendingBytes != 0 || len(f.pending) != 0 {
+					t.Errorf("%d bytes in %d segments left pending", f.pendingBytes, len(f.pending))
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,325 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+// capturePacket is a packet of a capture file, Data starts with the link layer header.
+type capturePacket struct {
+	Time     time.Time
+	LinkType uint32
+	Data     []byte
+}
+
+// captureReader returns the packets of a capture file one by one, Next returns io.EOF at the end.
+type captureReader interface {
+	Next() (capturePacket, error)
+}
+
+// newCaptureReader detects whether r is a pcap or a pcapng file.
+func newCaptureReader(r io.Reader) (captureReader, error) {
+	br := bufio.NewReaderSize(r, 1<<16)
+	magic, err := br.Peek(4)
+	if err != nil {
+		return nil, errors.New("pcap: file is too short")
+	}
+	switch binary.LittleEndian.Uint32(magic) {
+	case 0x0a0d0d0a:
+		return &pcapngReader{r: br}, nil
+	case 0xa1b2c3d4, 0xd4c3b2a1, 0xa1b23c4d, 0x4d3cb2a1:
+		return newPcapReader(br)
+	}
+	return nil, fmt.Errorf("pcap: unknown file format, magic %x", magic)
+}
+
+type pcapReader struct {
+	r        io.Reader
+	order    binary.ByteOrder
+	nano     bool
+	linkType uint32
+}
+
+func newPcapReader(r io.Reader) (*pcapReader, error) {
+	header := make([]byte, 24)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("pcap: file header: %s", err)
+	}
+	p := &pcapReader{r: r, order: binary.LittleEndian}
+	magic := binary.LittleEndian.Uint32(header)
+	if magic == 0xd4c3b2a1 || magic == 0x4d3cb2a1 {
+		p.order = binary.BigEndian
+	}
+	p.nano = magic == 0xa1b23c4d || magic == 0x4d3cb2a1
+	p.linkType = p.order.Uint32(header[20:]) & 0xffff
+	return p, nil
+}
+
+func (p *pcapReader) Next() (capturePacket, error) {
+	header := make([]byte, 16)
+	if _, err := io.ReadFull(p.r, header); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			err = errors.New("pcap: truncated record header")
+		}
+		return capturePacket{}, err
+	}
+	sec, frac := p.order.Uint32(header), p.order.Uint32(header[4:])
+	size := p.order.Uint32(header[8:])
+	if size > 1<<26 {
+		return capturePacket{}, fmt.Errorf("pcap: record of %d bytes is too large", size)
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(p.r, data); err != nil {
+		return capturePacket{}, errors.New("pcap: truncated record")
+	}
+	nsec := int64(frac)
+	if !p.nano {
+		nsec *= 1000
+	}
+	return capturePacket{Time: time.Unix(int64(sec), nsec), LinkType: p.linkType, Data: data}, nil
+}
+
+type pcapngInterface struct {
+	linkType uint32
+	// unitsPerSecond is the timestamp resolution.
+	unitsPerSecond uint64
+}
+
+type pcapngReader struct {
+	r          io.Reader
+	order      binary.ByteOrder
+	interfaces []pcapngInterface
+}
+
+func (p *pcapngReader) Next() (capturePacket, error) {
+	for {
+		head := make([]byte, 8)
+		if _, err := io.ReadFull(p.r, head); err != nil {
+			if err == io.ErrUnexpectedEOF {
+				err = errors.New("pcapng: truncated block header")
+			}
+			return capturePacket{}, err
+		}
+		blockType := binary.LittleEndian.Uint32(head)
+		if blockType == 0x0a0d0d0a {
+			// the section header defines the byte order of the blocks following it
+			bom := make([]byte, 4)
+			if _, err := io.ReadFull(p.r, bom); err != nil {
+				return capturePacket{}, errors.New("pcapng: truncated section header")
+			}
+			p.order = binary.LittleEndian
+			if binary.BigEndian.Uint32(bom) == 0x1a2b3c4d {
+				p.order = binary.BigEndian
+			}
+			p.interfaces = nil
+			length := p.order.Uint32(head[4:])
+			if length < 16 {
+				return capturePacket{}, errors.New("pcapng: invalid section header length")
+			}
+			if _, err := io.CopyN(io.Discard, p.r, int64(length-12)); err != nil {
+				return capturePacket{}, errors.New("pcapng: truncated section header")
+			}
+			continue
+		}
+		if p.order == nil {
+			return capturePacket{}, errors.New("pcapng: missing section header")
+		}
+		length := p.order.Uint32(head[4:])
+		if length < 12 || length > 1<<26 {
+			return capturePacket{}, fmt.Errorf("pcapng: invalid block length %d", length)
+		}
+		body := make([]byte, length-8)
+		if _, err := io.ReadFull(p.r, body); err != nil {
+			return capturePacket{}, errors.New("pcapng: truncated block")
+		}
+		body = body[:len(body)-4]
+		blockType = p.order.Uint32(head)
+		switch blockType {
+		case 1:
+			if err := p.addInterface(body); err != nil {
+				return capturePacket{}, err
+			}
+		case 2, 3, 6:
+			if packet, ok, err := p.packet(blockType, body); err != nil || ok {
+				return packet, err
+			}
+		}
+	}
+}
+
+func (p *pcapngReader) addInterface(body []byte) error {
+	if len(body) < 8 {
+		return errors.New("pcapng: truncated interface description")
+	}
+	iface := pcapngInterface{linkType: uint32(p.order.Uint16(body)), unitsPerSecond: 1000000}
+	for options := body[8:]; len(options) >= 4; {
+		code, size := p.order.Uint16(options), int(p.order.Uint16(options[2:]))
+		if code == 0 || 4+size > len(options) {
+			break
+		}
+		if code == 9 && size >= 1 {
+			// if_tsresol, a power of 10 or, with the high bit set, of 2
+			resolution := options[4]
+			iface.unitsPerSecond = 1
+			for i := 0; i < int(resolution&0x7f); i++ {
+				if resolution&0x80 != 0 {
+					iface.unitsPerSecond *= 2
+				} else {
+					iface.unitsPerSecond *= 10
+				}
+			}
+		}
+		options = options[4+(size+3)&^3:]
+	}
+	p.interfaces = append(p.interfaces, iface)
+	return nil
+}
+
+// packet returns the packet of an enhanced (6), simple (3) or obsolete (2) packet block.
+func (p *pcapngReader) packet(blockType uint32, body []byte) (capturePacket, bool, error) {
+	var ifaceID, size int
+	var timestamp uint64
+	var data []byte
+	switch blockType {
+	case 3:
+		if len(body) < 4 {
+			return capturePacket{}, false, errors.New("pcapng: truncated simple packet block")
+		}
+		size, data = int(p.order.Uint32(body)), body[4:]
+	default:
+		if len(body) < 20 {
+			return capturePacket{}, false, errors.New("pcapng: truncated packet block")
+		}
+		if blockType == 6 {
+			ifaceID = int(p.order.Uint32(body))
+		} else {
+			ifaceID = int(p.order.Uint16(body))
+		}
+		timestamp = uint64(p.order.Uint32(body[4:]))<<32 | uint64(p.order.Uint32(body[8:]))
+		size, data = int(p.order.Uint32(body[12:])), body[20:]
+	}
+	if ifaceID >= len(p.interfaces) {
+		return capturePacket{}, false, fmt.Errorf("pcapng: packet of unknown interface %d", ifaceID)
+	}
+	if size < len(data) {
+		data = data[:size]
+	}
+	iface := p.interfaces[ifaceID]
+	sec := timestamp / iface.unitsPerSecond
+	nsec := timestamp % iface.unitsPerSecond * 1000000000 / iface.unitsPerSecond
+	return capturePacket{Time: time.Unix(int64(sec), int64(nsec)), LinkType: iface.linkType, Data: data}, true, nil
+}
+
+// tcpSegment is the TCP part of a captured packet.
+type tcpSegment struct {
+	Src, Dst   string
+	SrcPort    uint16
+	DstPort    uint16
+	Seq        uint32
+	SYN, FIN   bool
+	RST        bool
+	Payload    []byte
+	Fragmented bool
+}
+
+// parseTCP extracts the TCP segment of a captured packet, ok is false for other packets.
+func parseTCP(linkType uint32, data []byte) (segment tcpSegment, ok bool) {
+	var ethertype uint16
+	switch linkType {
+	case 1: // Ethernet
+		if len(data) < 14 {
+			return segment, false
+		}
+		ethertype, data = binary.BigEndian.Uint16(data[12:]), data[14:]
+		for (ethertype == 0x8100 || ethertype == 0x88a8) && len(data) >= 4 {
+			ethertype, data = binary.BigEndian.Uint16(data[2:]), data[4:]
+		}
+	case 0, 108: // BSD loopback, the family is in host or network byte order
+		if len(data) < 4 {
+			return segment, false
+		}
+		family := binary.LittleEndian.Uint32(data)
+		if family > 0xffff {
+			family = binary.BigEndian.Uint32(data)
+		}
+		ethertype, data = 0x86dd, data[4:]
+		if family == 2 {
+			ethertype = 0x0800
+		}
+	case 12, 14, 101: // raw IP
+		if len(data) == 0 {
+			return segment, false
+		}
+		ethertype = 0x86dd
+		if data[0]>>4 == 4 {
+			ethertype = 0x0800
+		}
+	case 113: // Linux cooked capture
+		if len(data) < 16 {
+			return segment, false
+		}
+		ethertype, data = binary.BigEndian.Uint16(data[14:]), data[16:]
+	case 276: // Linux cooked capture v2
+		if len(data) < 20 {
+			return segment, false
+		}
+		ethertype, data = binary.BigEndian.Uint16(data), data[20:]
+	default:
+		return segment, false
+	}
+	var protocol byte
+	switch ethertype {
+	case 0x0800:
+		if len(data) < 20 || data[0]>>4 != 4 {
+			return segment, false
+		}
+		headerLength, total := int(data[0]&0x0f)*4, int(binary.BigEndian.Uint16(data[2:]))
+		if headerLength < 20 || total < headerLength || total > len(data) {
+			return segment, false
+		}
+		segment.Fragmented = binary.BigEndian.Uint16(data[6:])&0x3fff != 0
+		protocol = data[9]
+		segment.Src, segment.Dst = net.IP(data[12:16]).String(), net.IP(data[16:20]).String()
+		data = data[headerLength:total]
+	case 0x86dd:
+		if len(data) < 40 || data[0]>>4 != 6 {
+			return segment, false
+		}
+		payloadLength := int(binary.BigEndian.Uint16(data[4:]))
+		if 40+payloadLength > len(data) {
+			return segment, false
+		}
+		protocol = data[6]
+		segment.Src, segment.Dst = net.IP(data[8:24]).String(), net.IP(data[24:40]).String()
+		data = data[40 : 40+payloadLength]
+		// skip the hop by hop, routing and destination options headers
+		for (protocol == 0 || protocol == 43 || protocol == 60) && len(data) >= 8 {
+			size := (int(data[1]) + 1) * 8
+			if size > len(data) {
+				return segment, false
+			}
+			protocol, data = data[0], data[size:]
+		}
+		segment.Fragmented = protocol == 44
+	default:
+		return segment, false
+	}
+	if protocol != 6 || segment.Fragmented || len(data) < 20 {
+		return segment, segment.Fragmented
+	}
+	offset := int(data[12]>>4) * 4
+	if offset < 20 || offset > len(data) {
+		return segment, false
+	}
+	segment.SrcPort, segment.DstPort = binary.BigEndian.Uint16(data), binary.BigEndian.Uint16(data[2:])
+	segment.Seq = binary.BigEndian.Uint32(data[4:])
+	flags := data[13]
+	segment.FIN, segment.SYN, segment.RST = flags&0x01 != 0, flags&0x02 != 0, flags&0x04 != 0
+	segment.Payload = data[offset:]
+	return segment, true
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// testPcap returns a pcap file of the records, captured a second apart.
+func testPcap(order binary.ByteOrder, nano bool, linkType uint32, records ...[]byte) []byte {
+	var b bytes.Buffer
+	magic := uint32(0xa1b2c3d4)
+	if nano {
+		magic = 0xa1b23c4d
+	}
+	put := func(v ...interface{}) {
+		for _, x := range v {
+			binary.Write(&b, order, x)
+		}
+	}
+	put(magic, uint16(2), uint16(4), int32(0), uint32(0), uint32(65535), linkType)
+	for i, data := range records {
+		put(uint32(1500000000+i), uint32(250), uint32(len(data)), uint32(len(data)))
+		b.Write(data)
+	}
+	return b.Bytes()
+}
+
+// testPcapngBlock returns a pcapng block with the body padded to 4 bytes.
+func testPcapngBlock(order binary.ByteOrder, blockType uint32, body []byte) []byte {
+	var b bytes.Buffer
+	padded := append(append([]byte(nil), body...), make([]byte, -len(body)&3)...)
+	binary.Write(&b, order, blockType)
+	binary.Write(&b, order, uint32(12+len(padded)))
+	b.Write(padded)
+	binary.Write(&b, order, uint32(12+len(padded)))
+	return b.Bytes()
+}
+
+func testPcapngSection(order binary.ByteOrder) []byte {
+	var body bytes.Buffer
+	binary.Write(&body, order, uint32(0x1a2b3c4d))
+	binary.Write(&body, order, uint16(1))
+	binary.Write(&body, order, uint16(0))
+	binary.Write(&body, order, int64(-1))
+	return testPcapngBlock(order, 0x0a0d0d0a, body.Bytes())
+}
+
+// testPcapngInterface returns an interface description block, tsresol is set if not 0.
+func testPcapngInterface(order binary.ByteOrder, linkType uint16, tsresol byte) []byte {
+	var body bytes.Buffer
+	binary.Write(&body, order, linkType)
+	binary.Write(&body, order, uint16(0))
+	binary.Write(&body, order, uint32(65535))
+	// an option before if_tsresol, which has to be skipped by its padded size
+	binary.Write(&body, order, uint16(2))
+	binary.Write(&body, order, uint16(3))
+	body.Write([]byte{'e', 't', 'h', 0})
+	if tsresol != 0 {
+		binary.Write(&body, order, uint16(9))
+		binary.Write(&body, order, uint16(1))
+		body.Write([]byte{tsresol, 0, 0, 0})
+	}
+	binary.Write(&body, order, uint32(0))
+	return testPcapngBlock(order, 1, body.Bytes())
+}
+
+func testPcapngEnhanced(order binary.ByteOrder, iface uint32, timestamp uint64, data []byte) []byte {
+	var body bytes.Buffer
+	binary.Write(&body, order, iface)
+	binary.Write(&body, order, uint32(timestamp>>32))
+	binary.Write(&body, order, uint32(timestamp))
+	binary.Write(&body, order, uint32(len(data)))
+	binary.Write(&body, order, uint32(len(data)))
+	body.Write(data)
+	return testPcapngBlock(order, 6, body.Bytes())
+}
+
+func testPcapngSimple(order binary.ByteOrder, data []byte) []byte {
+	var body bytes.Buffer
+	binary.Write(&body, order, uint32(len(data)))
+	body.Write(data)
+	return testPcapngBlock(order, 3, body.Bytes())
+}
+
+func concat(parts ...[]byte) []byte { return bytes.Join(parts, nil) }
+
+func TestCaptureReader(t *testing.T) {
+	be, le := binary.BigEndian, binary.LittleEndian
+	data1, data2 := []byte{1, 2, 3, 4, 5}, []byte{6, 7}
+	tests := []struct {
+		name string
+		file []byte
+		want []capturePacket
+	}{
+		{"pcap little endian", testPcap(le, false, 1, data1, data2), []capturePacket{
+			{time.Unix(1500000000, 250000), 1, data1},
+			{time.Unix(1500000001, 250000), 1, data2},
+		}},
+		{"pcap big endian", testPcap(be, false, 113, data1), []capturePacket{
+			{time.Unix(1500000000, 250000), 113, data1},
+		}},
+		{"pcap nanoseconds", testPcap(le, true, 101, data1), []capturePacket{
+			{time.Unix(1500000000, 250), 101, data1},
+		}},
+		{"pcap big endian nanoseconds", testPcap(be, true, 0, data2), []capturePacket{
+			{time.Unix(1500000000, 250), 0, data2},
+		}},
+		{"pcapng little endian", concat(testPcapngSection(le), testPcapngInterface(le, 1, 0),
+			testPcapngEnhanced(le, 0, 1500000000*1000000+250, data1)), []capturePacket{
+			{time.Unix(1500000000, 250000), 1, data1},
+		}},
+		{"pcapng big endian", concat(testPcapngSection(be), testPcapngInterface(be, 113, 0),
+			testPcapngEnhanced(be, 0, 1500000000*1000000+250, data1), testPcapngSimple(be, data2)), []capturePacket{
+			{time.Unix(1500000000, 250000), 113, data1},
+			{time.Unix(0, 0), 113, data2},
+		}},
+		{"pcapng if_tsresol power of 10", concat(testPcapngSection(le), testPcapngInterface(le, 1, 9),
+			testPcapngEnhanced(le, 0, 1500000000*1000000000+123456789, data1)), []capturePacket{
+			{time.Unix(1500000000, 123456789), 1, data1},
+		}},
+		{"pcapng if_tsresol power of 2", concat(testPcapngSection(be), testPcapngInterface(be, 1, 0x8a),
+			testPcapngEnhanced(be, 0, 1500000000*1024+256, data1)), []capturePacket{
+			{time.Unix(1500000000, 250000000), 1, data1},
+		}},
+		{"pcapng interfaces", concat(testPcapngSection(le), testPcapngInterface(le, 1, 0), testPcapngInterface(le, 276, 3),
+			testPcapngEnhanced(le, 1, 1500000000*1000+5, data1), testPcapngEnhanced(le, 0, 7, data2)), []capturePacket{
+			{time.Unix(1500000000, 5000000), 276, data1},
+			{time.Unix(0, 7000), 1, data2},
+		}},
+		{"pcapng sections", concat(testPcapngSection(le), testPcapngInterface(le, 1, 0), testPcapngEnhanced(le, 0, 1, data1),
+			testPcapngSection(be), testPcapngInterface(be, 113, 0), testPcapngEnhanced(be, 0, 2, data2)), []capturePacket{
+			{time.Unix(0, 1000), 1, data1},
+			{time.Unix(0, 2000), 113, data2},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := newCaptureReader(bytes.NewReader(tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []capturePacket
+			for {
+				packet, err := r.Next()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, packet)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d packets, want %d", len(got), len(tt.want))
+			}
+			for i := range got {
+				if !got[i].Time.Equal(tt.want[i].Time) || got[i].LinkType != tt.want[i].LinkType || !bytes.Equal(got[i].Data, tt.want[i].Data) {
+					t.Errorf("packet %d: got %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCaptureReaderErrors(t *testing.T) {
+	le := binary.LittleEndian
+	file := testPcap(le, false, 1, []byte{1, 2, 3, 4})
+	tests := []struct {
+		name string
+		file []byte
+	}{
+		{"too short", []byte{0xd4, 0xc3}},
+		{"unknown magic", []byte("GIF89a")},
+		{"pcap truncated file header", file[:20]},
+		{"pcap truncated record header", file[:30]},
+		{"pcap truncated record", file[:len(file)-1]},
+		{"pcapng missing interface", concat(testPcapngSection(le), testPcapngEnhanced(le, 0, 1, []byte{1}))},
+		{"pcapng truncated block", concat(testPcapngSection(le), testPcapngInterface(le, 1, 0))[:40]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := newCaptureReader(bytes.NewReader(tt.file))
+			for err == nil {
+				_, err = r.Next()
+			}
+			if err == io.EOF {
+				t.Error("no error")
+			}
+		})
+	}
+}
+
+// testTCP returns a TCP header without options followed by payload.
+func testTCP(srcPort, dstPort uint16, seq uint32, flags byte, payload []byte) []byte {
+	b := make([]byte, 20, 20+len(payload))
+	binary.BigEndian.PutUint16(b, srcPort)
+	binary.BigEndian.PutUint16(b[2:], dstPort)
+	binary.BigEndian.PutUint32(b[4:], seq)
+	b[12], b[13] = 5<<4, flags
+	return append(b, payload...)
+}
+
+// testIPv4 returns an IPv4 packet from 10.0.0.1 to 10.0.0.2, fragment is the flags and offset field.
+func testIPv4(protocol byte, fragment uint16, payload []byte) []byte {
+	b := make([]byte, 20, 20+len(payload))
+	b[0] = 0x45
+	binary.BigEndian.PutUint16(b[2:], uint16(20+len(payload)))
+	binary.BigEndian.PutUint16(b[6:], fragment)
+	b[8], b[9] = 64, protocol
+	copy(b[12:], []byte{10, 0, 0, 1, 10, 0, 0, 2})
+	return append(b, payload...)
+}
+
+// testIPv6 returns an IPv6 packet from ::1 to ::2 with a hop by hop options header.
+func testIPv6(protocol byte, payload []byte) []byte {
+	b := make([]byte, 48, 48+len(payload))
+	b[0] = 0x60
+	binary.BigEndian.PutUint16(b[4:], uint16(8+len(payload)))
+	b[6], b[7] = 0, 64
+	b[23], b[39] = 1, 2
+	b[40] = protocol
+	return append(b, payload...)
+}
+
+func testEthernet(ethertype uint16, vlans int, payload []byte) []byte {
+	b := make([]byte, 12)
+	for i := 0; i < vlans; i++ {
+		b = append(b, 0x81, 0x00, 0, byte(i+1))
+	}
+	b = append(b, byte(ethertype>>8), byte(ethertype))
+	return append(b, payload...)
+}
+
+func TestParseTCP(t *testing.T) {
+	payload := []byte("data")
+	tcp := testTCP(40000, 9000, 1000, 0x18, payload)
+	ipv4 := testIPv4(6, 0x4000, tcp)
+	ipv6 := testIPv6(6, tcp)
+	v4 := tcpSegment{Src: "10.0.0.1", Dst: "10.0.0.2", SrcPort: 40000, DstPort: 9000, Seq: 1000, Payload: payload}
+	v6 := v4
+	v6.Src, v6.Dst = "::1", "::2"
+	sll := append(make([]byte, 14), 0x08, 0x00)
+	sll2 := append([]byte{0x86, 0xdd}, make([]byte, 18)...)
+	tests := []struct {
+		name     string
+		linkType uint32
+		data     []byte
+		want     tcpSegment
+		ok       bool
+	}{
+		{"ethernet", 1, testEthernet(0x0800, 0, ipv4), v4, true},
+		{"ethernet ipv6", 1, testEthernet(0x86dd, 0, ipv6), v6, true},
+		{"vlan", 1, testEthernet(0x0800, 1, ipv4), v4, true},
+		{"double vlan", 1, testEthernet(0x86dd, 2, ipv6), v6, true},
+		{"sll", 113, append(sll, ipv4...), v4, true},
+		{"sll2", 276, append(sll2, ipv6...), v6, true},
+		{"loopback host order", 0, append([]byte{2, 0, 0, 0}, ipv4...), v4, true},
+		{"loopback network order", 108, append([]byte{0, 0, 0, 2}, ipv4...), v4, true},
+		{"loopback ipv6", 0, append([]byte{30, 0, 0, 0}, ipv6...), v6, true},
+		{"raw ipv4", 101, ipv4, v4, true},
+		{"raw ipv6", 12, ipv6, v6, true},
+		{"flags", 101, testIPv4(6, 0, testTCP(9000, 40000, 7, 0x07, nil)),
+			tcpSegment{Src: "10.0.0.1", Dst: "10.0.0.2", SrcPort: 9000, DstPort: 40000, Seq: 7, SYN: true, FIN: true, RST: true, Payload: []byte{}}, true},
+		{"fragment", 101, testIPv4(6, 0x2000, tcp), tcpSegment{Src: "10.0.0.1", Dst: "10.0.0.2", Fragmented: true}, true},
+		{"udp", 101, testIPv4(17, 0, tcp), tcpSegment{}, false},
+		{"arp", 1, testEthernet(0x0806, 0, ipv4), tcpSegment{}, false},
+		{"unknown link type", 147, ipv4, tcpSegment{}, false},
+		{"truncated ethernet", 1, make([]byte, 13), tcpSegment{}, false},
+		{"truncated sll", 113, sll[:15], tcpSegment{}, false},
+		{"truncated ip", 101, ipv4[:len(ipv4)-1], tcpSegment{}, false},
+		{"truncated tcp", 101, testIPv4(6, 0, tcp[:19]), tcpSegment{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseTCP(tt.linkType, tt.data)
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, want %v", ok, tt.ok)
+			}
+			if ok && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
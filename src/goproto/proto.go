@@ -0,0 +1,61 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"generator"
+	"io/ioutil"
+	"os"
+)
+
+// exportProto writes the protocol as a .proto file, it is the proto backend.
+func exportProto(args []string) error {
+	flags := flag.NewFlagSet("export-proto", flag.ExitOnError)
+	src := flags.String("src", "", "set protocol file path")
+	dest := flags.String("dest", "", "the .proto file to write")
+	templates := flags.String("templates", "", templatesUsage("proto"))
+	opts := make(optionFlags)
+	flags.Var(opts, "opt", "option as key=value, may be repeated: package, go_package")
+	flags.Parse(args)
+	if len(*src) == 0 || len(*dest) == 0 {
+		return errors.New("export-proto: -src and -dest are required")
+	}
+	if len(*templates) != 0 {
+		opts["templates"] = *templates
+	}
+	files, err := generator.GenerateWith("proto", *src, generator.Options(opts))
+	if err != nil {
+		return err
+	}
+	if err = writeFiles(*dest, files); err != nil {
+		return err
+	}
+	fmt.Println("Complete!")
+	return nil
+}
+
+// importProto converts a .proto file into a protocol definition file.
+func importProto(args []string) error {
+	flags := flag.NewFlagSet("import-proto", flag.ExitOnError)
+	src := flags.String("src", "", "the .proto file to read")
+	dest := flags.String("dest", "", "the protocol definition file to write")
+	pkg := flags.String("package", "", "Go package name, defaults to the last part of the protobuf package")
+	flags.Parse(args)
+	if len(*src) == 0 || len(*dest) == 0 {
+		return errors.New("import-proto: -src and -dest are required")
+	}
+	data, err := ioutil.ReadFile(*src)
+	if err != nil {
+		return err
+	}
+	code, err := generator.ImportProto(data, generator.Options{"package": *pkg})
+	if err != nil {
+		return err
+	}
+	if err = ioutil.WriteFile(*dest, code, os.ModePerm); err != nil {
+		return err
+	}
+	fmt.Println("Complete!")
+	return nil
+}
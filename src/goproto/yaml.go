@@ -0,0 +1,479 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"generator"
+	"strconv"
+	"strings"
+)
+
+// yamlSubset describes the YAML parseYAML supports, it is part of the help of the encode command.
+const yamlSubset = `YAML input is limited to the subset needed to describe packets:
+  block mappings and sequences indented by spaces, flow collections on one line ([1, 2], {X: 1}),
+  single line plain, 'single' and "double" quoted scalars, # comments, and documents separated by ---.
+  Anchors, aliases, tags, directives, complex keys, block scalars (| and >) and scalars or
+  flow collections spanning several lines are rejected.`
+
+// parseYAML parses the YAML subset needed to describe packets, see yamlSubset, and rejects
+// the features outside it with an error instead of reading them differently from YAML.
+// Plain scalars other than null are kept as yamlPlain, their type depends on the field they
+// are written to, see resolveYAMLFields.
+func parseYAML(data []byte) ([]interface{}, error) {
+	var docs []interface{}
+	var lines []yamlLine
+	flush := func() error {
+		if len(lines) == 0 {
+			return nil
+		}
+		p := &yamlParser{lines: lines}
+		doc, err := p.parseNode(lines[0].indent)
+		if err != nil {
+			return err
+		}
+		if p.pos < len(p.lines) {
+			return fmt.Errorf("yaml: line %d: unexpected indentation", p.lines[p.pos].num)
+		}
+		docs = append(docs, doc)
+		lines = nil
+		return nil
+	}
+	for i, text := range strings.Split(string(data), "\n") {
+		text = strings.TrimRight(stripYAMLComment(text), " \t\r")
+		trimmed := strings.TrimLeft(text, " ")
+		switch {
+		case len(trimmed) == 0:
+			continue
+		case text == "---" || text == "...":
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			continue
+		case strings.HasPrefix(text, "--- "):
+			return nil, fmt.Errorf("yaml: line %d: content after --- is not supported, start it on the next line", i+1)
+		case trimmed[0] == '\t':
+			return nil, fmt.Errorf("yaml: line %d: tabs are not allowed for indentation", i+1)
+		}
+		lines = append(lines, yamlLine{indent: len(text) - len(trimmed), text: trimmed, num: i + 1})
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
+
+// stripYAMLComment removes a comment, which starts with # at the line start or after a space, outside quotes.
+func stripYAMLComment(text string) string {
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || text[i-1] == ' ' || text[i-1] == '\t'):
+			return text[:i]
+		}
+	}
+	return text
+}
+
+type yamlLine struct {
+	indent int
+	text   string
+	num    int
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+func isYAMLItem(text string) bool { return text == "-" || strings.HasPrefix(text, "- ") }
+
+func (p *yamlParser) parseNode(indent int) (interface{}, error) {
+	line := p.lines[p.pos]
+	if isYAMLItem(line.text) {
+		return p.parseSequence(indent)
+	}
+	if _, _, ok := splitYAMLKey(line.text); ok {
+		return p.parseMapping(indent)
+	}
+	p.pos++
+	return parseYAMLInline(line.text, line.num)
+}
+
+func (p *yamlParser) parseSequence(indent int) ([]interface{}, error) {
+	items := []interface{}{}
+	for p.pos < len(p.lines) && p.lines[p.pos].indent == indent && isYAMLItem(p.lines[p.pos].text) {
+		line := &p.lines[p.pos]
+		content := strings.TrimLeft(line.text[1:], " ")
+		var item interface{}
+		var err error
+		if len(content) == 0 {
+			p.pos++
+			if p.pos < len(p.lines) && p.lines[p.pos].indent > indent {
+				item, err = p.parseNode(p.lines[p.pos].indent)
+			}
+		} else {
+			// the item content continues the block at its own column
+			line.indent += len(line.text) - len(content)
+			line.text = content
+			_, _, isMapping := splitYAMLKey(content)
+			if item, err = p.parseNode(line.indent); err == nil && !isMapping && !isYAMLItem(content) {
+				err = p.checkSingleLine(indent)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func (p *yamlParser) parseMapping(indent int) (map[string]interface{}, error) {
+	m := make(map[string]interface{})
+	for p.pos < len(p.lines) && p.lines[p.pos].indent == indent {
+		line := p.lines[p.pos]
+		key, rest, ok := splitYAMLKey(line.text)
+		if !ok {
+			return nil, fmt.Errorf("yaml: line %d: expected key: value", line.num)
+		}
+		if err := checkYAMLIndicator(line.text); err != nil {
+			return nil, fmt.Errorf("yaml: line %d: %s", line.num, err)
+		}
+		if _, dup := m[key]; dup {
+			return nil, fmt.Errorf("yaml: line %d: duplicate key %q", line.num, key)
+		}
+		p.pos++
+		var value interface{}
+		var err error
+		if len(rest) != 0 {
+			if value, err = parseYAMLInline(rest, line.num); err == nil {
+				err = p.checkSingleLine(indent)
+			}
+		} else if p.pos < len(p.lines) {
+			next := p.lines[p.pos]
+			if next.indent > indent || (next.indent == indent && isYAMLItem(next.text)) {
+				value, err = p.parseNode(next.indent)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		m[key] = value
+	}
+	if p.pos < len(p.lines) && p.lines[p.pos].indent > indent {
+		return nil, fmt.Errorf("yaml: line %d: unexpected indentation", p.lines[p.pos].num)
+	}
+	return m, nil
+}
+
+// checkSingleLine returns an error if the line after an inline value is indented deeper than
+// the line of the value, which continues the value in YAML.
+func (p *yamlParser) checkSingleLine(indent int) error {
+	if p.pos < len(p.lines) && p.lines[p.pos].indent > indent {
+		return fmt.Errorf("yaml: line %d: values spanning several lines are not supported", p.lines[p.pos].num)
+	}
+	return nil
+}
+
+// splitYAMLKey splits "key: value" and "key:", the key may be quoted.
+func splitYAMLKey(text string) (key, rest string, ok bool) {
+	if len(text) == 0 || strings.ContainsRune("[{", rune(text[0])) {
+		return "", "", false
+	}
+	end := 0
+	if text[0] == '"' || text[0] == '\'' {
+		end = strings.IndexByte(text[1:], text[0]) + 2
+		if end < 2 || end >= len(text) || text[end] != ':' {
+			return "", "", false
+		}
+		unquoted, err := parseYAMLScalar(text[:end])
+		if err != nil {
+			return "", "", false
+		}
+		key = fmt.Sprint(unquoted)
+	} else {
+		if end = strings.Index(text, ": "); end < 0 {
+			if !strings.HasSuffix(text, ":") {
+				return "", "", false
+			}
+			end = len(text) - 1
+		}
+		key = strings.TrimSpace(text[:end])
+	}
+	return key, strings.TrimSpace(text[end+1:]), true
+}
+
+func parseYAMLInline(text string, num int) (interface{}, error) {
+	f := &yamlFlow{text: text}
+	v, err := f.value()
+	if err == nil {
+		f.skipSpace()
+		if f.pos < len(f.text) {
+			err = fmt.Errorf("unexpected %q", f.text[f.pos:])
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("yaml: line %d: %s", num, err)
+	}
+	return v, nil
+}
+
+// yamlFlow parses a flow value: [a, b], {k: v} or a scalar.
+type yamlFlow struct {
+	text  string
+	pos   int
+	depth int
+}
+
+func (f *yamlFlow) skipSpace() {
+	for f.pos < len(f.text) && f.text[f.pos] == ' ' {
+		f.pos++
+	}
+}
+
+func (f *yamlFlow) value() (interface{}, error) {
+	f.skipSpace()
+	if f.pos >= len(f.text) {
+		return nil, nil
+	}
+	switch f.text[f.pos] {
+	case '[':
+		f.pos++
+		items := []interface{}{}
+		for {
+			f.skipSpace()
+			if f.pos >= len(f.text) {
+				return nil, errFlowLine
+			}
+			if f.text[f.pos] == ']' {
+				f.pos++
+				return items, nil
+			}
+			f.depth++
+			item, err := f.value()
+			f.depth--
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+			if err = f.separator(']'); err != nil {
+				return nil, err
+			}
+		}
+	case '{':
+		f.pos++
+		m := make(map[string]interface{})
+		for {
+			f.skipSpace()
+			if f.pos >= len(f.text) {
+				return nil, errFlowLine
+			}
+			if f.text[f.pos] == '}' {
+				f.pos++
+				return m, nil
+			}
+			f.depth++
+			key, err := f.value()
+			if err == nil {
+				f.skipSpace()
+				if f.pos >= len(f.text) || f.text[f.pos] != ':' {
+					err = fmt.Errorf("expected : after key %v", key)
+				}
+			}
+			var value interface{}
+			if err == nil {
+				f.pos++
+				value, err = f.value()
+			}
+			f.depth--
+			if err != nil {
+				return nil, err
+			}
+			m[fmt.Sprint(key)] = value
+			if err = f.separator('}'); err != nil {
+				return nil, err
+			}
+		}
+	}
+	start := f.pos
+	if c := f.text[f.pos]; c == '"' || c == '\'' {
+		for f.pos++; f.pos < len(f.text); f.pos++ {
+			if c == '"' && f.text[f.pos] == '\\' {
+				f.pos++
+			} else if f.text[f.pos] == c {
+				// '' is a quote in a single-quoted scalar
+				if c == '"' || f.pos+1 >= len(f.text) || f.text[f.pos+1] != '\'' {
+					break
+				}
+				f.pos++
+			}
+		}
+		f.pos++
+		if f.pos > len(f.text) {
+			return nil, fmt.Errorf("unterminated string, quoted scalars must end on the same line")
+		}
+	} else {
+		if err := checkYAMLIndicator(f.text[f.pos:]); err != nil {
+			return nil, err
+		}
+		stop := ""
+		if f.depth > 0 {
+			stop = ",]}:"
+		}
+		for f.pos < len(f.text) && !strings.ContainsRune(stop, rune(f.text[f.pos])) {
+			f.pos++
+		}
+	}
+	return parseYAMLScalar(strings.TrimSpace(f.text[start:f.pos]))
+}
+
+var errFlowLine = errors.New("unterminated flow collection, it must end on the same line")
+
+// yamlIndicators are the characters starting YAML features parseYAML does not support, a
+// plain scalar cannot start with them.
+var yamlIndicators = map[byte]string{
+	'&': "anchors are",
+	'*': "aliases are",
+	'!': "tags are",
+	'|': "block scalars are",
+	'>': "block scalars are",
+	'%': "directives are",
+	'@': "the reserved indicator @ is",
+	'`': "the reserved indicator ` is",
+}
+
+// checkYAMLIndicator returns an error if the plain scalar starting text uses a YAML feature
+// outside the supported subset.
+func checkYAMLIndicator(text string) error {
+	if len(text) == 0 {
+		return nil
+	}
+	if what, ok := yamlIndicators[text[0]]; ok {
+		return fmt.Errorf("%s not supported", what)
+	}
+	if text == "?" || strings.HasPrefix(text, "? ") {
+		return errors.New("complex keys are not supported")
+	}
+	return nil
+}
+
+func (f *yamlFlow) separator(end byte) error {
+	f.skipSpace()
+	if f.pos < len(f.text) && f.text[f.pos] == ',' {
+		f.pos++
+		return nil
+	}
+	if f.pos < len(f.text) && f.text[f.pos] == end {
+		return nil
+	}
+	return fmt.Errorf("expected , or %c", end)
+}
+
+func parseYAMLScalar(text string) (interface{}, error) {
+	if len(text) >= 2 && text[0] == '"' && text[len(text)-1] == '"' {
+		return strconv.Unquote(text)
+	}
+	if len(text) >= 2 && text[0] == '\'' && text[len(text)-1] == '\'' {
+		return strings.Replace(text[1:len(text)-1], "''", "'", -1), nil
+	}
+	switch text {
+	case "", "~", "null", "Null", "NULL":
+		return nil, nil
+	}
+	return yamlPlain(text), nil
+}
+
+// yamlPlain is the text of a plain scalar, which is a string or a byte slice in base64 if the
+// field it is written to is one, and a number, a bool or a string by its form otherwise.
+type yamlPlain string
+
+// resolve returns the value of the scalar by its form: true and false become a bool, text
+// which looks like a number a json.Number, like decoding JSON with UseNumber.
+func (s yamlPlain) resolve() interface{} {
+	text := string(s)
+	switch text {
+	case "true", "True", "TRUE":
+		return true
+	case "false", "False", "FALSE":
+		return false
+	}
+	if _, err := strconv.ParseFloat(text, 64); err == nil {
+		return json.Number(text)
+	}
+	if _, err := strconv.ParseInt(text, 0, 64); err == nil {
+		return json.Number(text)
+	}
+	if _, err := strconv.ParseUint(text, 0, 64); err == nil {
+		return json.Number(text)
+	}
+	return text
+}
+
+// resolveYAML replaces the plain scalars in v by their values by form.
+func resolveYAML(v interface{}) interface{} {
+	switch v := v.(type) {
+	case yamlPlain:
+		return v.resolve()
+	case []interface{}:
+		for i := range v {
+			v[i] = resolveYAML(v[i])
+		}
+	case map[string]interface{}:
+		for key, value := range v {
+			v[key] = resolveYAML(value)
+		}
+	}
+	return v
+}
+
+// resolveYAMLFields replaces the plain scalars in the field values of p by the kind of the
+// field, so that 007 stays the text "007" in a string field. Members which are no fields of
+// p are resolved by form.
+func resolveYAMLFields(schema *generator.Schema, p *generator.PacketLayout, fields map[string]interface{}) {
+	for _, f := range p.Fields() {
+		v, ok := fields[f.Name()]
+		if !ok {
+			continue
+		}
+		switch f.Kind() {
+		case generator.SliceFieldKind, generator.ArrayFieldKind:
+			if list, ok := v.([]interface{}); ok {
+				for i := range list {
+					list[i] = resolveYAMLValue(schema, f.ElemKind(), f.TypeName(), list[i])
+				}
+			} else if k := f.ElemKind(); k == generator.ByteFieldKind || k == generator.Uint8FieldKind {
+				// byte slices are written as base64 text
+				v = resolveYAMLValue(schema, generator.StringFieldKind, "", v)
+			}
+			fields[f.Name()] = v
+		default:
+			fields[f.Name()] = resolveYAMLValue(schema, f.Kind(), f.TypeName(), v)
+		}
+	}
+	resolveYAML(fields)
+}
+
+// resolveYAMLValue resolves a single value of kind k, typeName names the struct of a struct kind.
+func resolveYAMLValue(schema *generator.Schema, k generator.FieldKind, typeName string, v interface{}) interface{} {
+	switch v := v.(type) {
+	case yamlPlain:
+		if k == generator.StringFieldKind {
+			return string(v)
+		}
+	case map[string]interface{}:
+		if p := schema.Lookup(typeName); k == generator.StructFieldKind && p != nil {
+			resolveYAMLFields(schema, p, v)
+		}
+	}
+	return resolveYAML(v)
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/json"
+	"generator"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseYAML(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want []interface{}
+	}{
+		{"scalars", "a: 1\nb: -0x10\nc: 1.5\nd: true\ne: ~\nf:\ng: text with spaces\n", []interface{}{
+			map[string]interface{}{"a": json.Number("1"), "b": json.Number("-0x10"), "c": json.Number("1.5"),
+				"d": true, "e": nil, "f": nil, "g": "text with spaces"},
+		}},
+		{"quoting", "a: \"007\"\nb: '1'\nc: 'it''s'\nd: \"tab\\there\"\n\"e f\": \"# no comment\"\n", []interface{}{
+			map[string]interface{}{"a": "007", "b": "1", "c": "it's", "d": "tab\there", "e f": "# no comment"},
+		}},
+		{"comments", "# header\na: 1 # trailing\nb: x#y\n  # indented\nc: 'a # b'\n", []interface{}{
+			map[string]interface{}{"a": json.Number("1"), "b": "x#y", "c": "a # b"},
+		}},
+		{"nesting", "a:\n  b:\n    c: 1\n  d: {e: 2, f: [3]}\n", []interface{}{
+			map[string]interface{}{"a": map[string]interface{}{
+				"b": map[string]interface{}{"c": json.Number("1")},
+				"d": map[string]interface{}{"e": json.Number("2"), "f": []interface{}{json.Number("3")}},
+			}},
+		}},
+		{"lists", "a:\n- 1\n- x: 2\n  y: 3\n-\n  - 4\nb: []\nc: [\"x, y\", z]\n", []interface{}{
+			map[string]interface{}{
+				"a": []interface{}{json.Number("1"), map[string]interface{}{"x": json.Number("2"), "y": json.Number("3")},
+					[]interface{}{json.Number("4")}},
+				"b": []interface{}{},
+				"c": []interface{}{"x, y", "z"},
+			},
+		}},
+		{"documents", "- a: 1\n---\nb: 2\n...\n", []interface{}{
+			[]interface{}{map[string]interface{}{"a": json.Number("1")}},
+			map[string]interface{}{"b": json.Number("2")},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			docs, err := parseYAML([]byte(tt.in))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := range docs {
+				docs[i] = resolveYAML(docs[i])
+			}
+			if !reflect.DeepEqual(docs, tt.want) {
+				t.Errorf("got %#v, want %#v", docs, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseYAMLErrors(t *testing.T) {
+	for _, in := range []string{
+		"a: 1\na: 2\n",
+		"a: 1\n   b: 2\n",
+		"a:\n\t- 1\n",
+		"a: |\n  text\n",
+		"a: [1, 2\n",
+		"a: {b 1}\n",
+		"a: \"open\n",
+	} {
+		if _, err := parseYAML([]byte(in)); err == nil {
+			t.Errorf("%q: no error", in)
+		}
+	}
+	// features outside the supported subset are named in the error
+	for in, want := range map[string]string{
+		"a: &x 1\nb: 2\n":          "anchors are not supported",
+		"a:\n  - *x\n":             "aliases are not supported",
+		"a: {b: *x}\n":             "aliases are not supported",
+		"&x a: 1\n":                "anchors are not supported",
+		"a: !!binary AAEC\n":       "tags are not supported",
+		"%YAML 1.2\n---\na: 1\n":   "directives are not supported",
+		"? a\n: 1\n":               "complex keys are not supported",
+		"a: |\n  text\n":           "block scalars are not supported",
+		"a: >-\n  text\n":          "block scalars are not supported",
+		"- |+\n  text\n":           "block scalars are not supported",
+		"a: @x\n":                  "reserved indicator @",
+		"a: first\n  second\n":     "values spanning several lines are not supported",
+		"- first\n  second\n":      "values spanning several lines are not supported",
+		"a: [1,\n  2]\n":           "flow collection, it must end on the same line",
+		"a: \"first\n  second\"\n": "quoted scalars must end on the same line",
+		"--- {a: 1}\n":             "content after --- is not supported",
+	} {
+		if _, err := parseYAML([]byte(in)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: got %v, want an error containing %q", in, err, want)
+		}
+	}
+}
+
+const yamlTestSchema = `package protocol
+
+type Point struct {
+	X    int32
+	Name string
+}
+
+// @Packet: LOGIN_REQUEST, 0x00000002
+type LoginRequest struct {
+	UserName string
+	Age      uint32
+	Raw      []byte
+	Names    []string
+	Pos      [2]Point
+}
+`
+
+func TestResolveYAMLFields(t *testing.T) {
+	schema, err := generator.ParseSchemaSource([]byte(yamlTestSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	docs, err := parseYAML([]byte(`
+UserName: 007
+Age: 0x1e
+Raw: "1234"
+Names: [true, 12, null]
+Pos:
+  - {X: 1, Name: 1.50}
+  - X: 2
+    Name: false
+Extra: 3
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := docs[0].(map[string]interface{})
+	resolveYAMLFields(schema, schema.Lookup("LoginRequest"), fields)
+	want := map[string]interface{}{
+		"UserName": "007",
+		"Age":      json.Number("0x1e"),
+		"Raw":      "1234",
+		"Names":    []interface{}{"true", "12", nil},
+		"Pos": []interface{}{
+			map[string]interface{}{"X": json.Number("1"), "Name": "1.50"},
+			map[string]interface{}{"X": json.Number("2"), "Name": "false"},
+		},
+		"Extra": json.Number("3"),
+	}
+	if !reflect.DeepEqual(fields, want) {
+		t.Errorf("got %#v, want %#v", fields, want)
+	}
+}